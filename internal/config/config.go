@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -14,6 +15,31 @@ type ScratchConfig struct {
 	TmuxinatorTemplate string `toml:"tmuxinator_template"`
 }
 
+// SubstitutionConfig defines a custom `%alias` substitution for kubectl
+// arguments. Exactly one of Value, Template or Command must be set.
+type SubstitutionConfig struct {
+	Aliases []string `toml:"aliases"`
+	// Static value
+	Value string `toml:"value"`
+	// Go template with .Context and .Namespace
+	Template string `toml:"template"`
+	// Shell command whose trimmed output is the value, templated like Template
+	Command string `toml:"command"`
+}
+
+type K8sConfig struct {
+	// Shells to probe, in order, when exec is invoked without a command
+	Shells []string `toml:"shells"`
+
+	Substitutions []SubstitutionConfig `toml:"substitutions"`
+
+	// Shell command that copies its stdin to the clipboard
+	ClipboardCommand string `toml:"clipboard_command"`
+
+	// Node label holding the cloud instance id, ie. i-0123456789abcdef0
+	InstanceIdLabel string `toml:"instance_id_label"`
+}
+
 type Config struct {
 	// Whether to automatically enable using cluster-admin role for non-read-only
 	// commands that require it in test kubecontexts
@@ -22,6 +48,8 @@ type Config struct {
 	Scratch ScratchConfig `toml:"scratch"`
 
 	WorkspaceDir string `toml:"workspace_dir"`
+
+	K8s K8sConfig `toml:"k8s"`
 }
 
 func NewConfig() Config {
@@ -43,7 +71,28 @@ func NewConfig() Config {
 			TmuxinatorTemplate: defaultTmuxinatorTemplate,
 		},
 		WorkspaceDir: defaultWorkspaceDir,
+		K8s: K8sConfig{
+			Shells:           []string{"bash", "sh", "ash"},
+			ClipboardCommand: defaultClipboardCommand(),
+			InstanceIdLabel:  "node.airbnb.com/instance-id",
+		},
+	}
+}
+
+func defaultClipboardCommand() string {
+	if runtime.GOOS == "darwin" {
+		return "pbcopy"
+	}
+	return "xclip -selection clipboard"
+}
+
+// FromMetadata returns the config stored in the cli.App metadata, falling back
+// to the default config if it is missing.
+func FromMetadata(metadata map[string]any) Config {
+	if cfg, ok := metadata["config"].(Config); ok {
+		return cfg
 	}
+	return NewConfig()
 }
 
 func NewConfigFromToml(filePath string) (Config, error) {
@@ -0,0 +1,152 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const appName = "mdcli"
+
+// Dir returns the directory used for persistent mdcli state, ie.
+// $XDG_STATE_HOME/mdcli or ~/.local/state/mdcli.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, appName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", appName), nil
+}
+
+// CacheDir returns the directory used for disposable mdcli caches.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// Path joins elem onto the state directory.
+func Path(elem ...string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// CachePath joins elem onto the cache directory.
+func CachePath(elem ...string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// ReadJSON decodes the JSON file at path into v. A missing file is not an
+// error and leaves v untouched.
+func ReadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse state file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// WriteJSON encodes v as JSON and atomically replaces the file at path,
+// creating parent directories as needed.
+func WriteJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpName)
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
+
+// ErrLocked is returned by TryWithLock when another process holds the lock.
+var ErrLocked = errors.New("state is locked")
+
+// WithLock runs fn while holding an exclusive lock on path, so concurrent
+// mdcli processes can safely read-modify-write the same state file.
+func WithLock(path string, fn func() error) error {
+	return withLock(path, syscall.LOCK_EX, fn)
+}
+
+// TryWithLock is WithLock, but returns ErrLocked instead of waiting if the
+// lock is held, for best effort updates which shouldn't block.
+func TryWithLock(path string, fn func() error) error {
+	return withLock(path, syscall.LOCK_EX|syscall.LOCK_NB, fn)
+}
+
+func withLock(path string, how int, fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = lock.Close()
+	}()
+
+	if err := syscall.Flock(int(lock.Fd()), how); errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	} else if err != nil {
+		return fmt.Errorf("failed to lock '%s': %w", path, err)
+	}
+	defer func() {
+		_ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	}()
+
+	return fn()
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config")
+
+	assert.NoError(t, WriteFileAtomic(path, []byte("old"), 0644))
+	assert.NoError(t, WriteFileAtomic(path, []byte("new"), 0600))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestTryWithLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	ran := false
+	err := WithLock(path, func() error {
+		return TryWithLock(path, func() error {
+			ran = true
+			return nil
+		})
+	})
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.False(t, ran)
+
+	err = TryWithLock(path, func() error {
+		ran = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestReadJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	v := map[string]int{"a": 1}
+	assert.NoError(t, ReadJSON(filepath.Join(dir, "missing.json"), &v))
+	assert.Equal(t, map[string]int{"a": 1}, v)
+
+	path := filepath.Join(dir, "state.json")
+	assert.NoError(t, WriteJSON(path, map[string]int{"b": 2}))
+	var read map[string]int
+	assert.NoError(t, ReadJSON(path, &read))
+	assert.Equal(t, map[string]int{"b": 2}, read)
+
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	assert.Error(t, ReadJSON(path, &read))
+}
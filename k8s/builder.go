@@ -7,6 +7,8 @@ import (
 
 type KubeBuilder struct {
 	Substitutions []Substitution
+	// Shell started by exec when no command is given. Defaults to bash.
+	Shell string
 }
 
 type Substitution struct {
@@ -51,22 +53,109 @@ func NewKubeBuilderWithSubstitutions(substitutions []Substitution) KubeBuilder {
 	return KubeBuilder{Substitutions: substitutions}
 }
 
+// AddSubstitutions appends substitutions to the builder. Earlier
+// substitutions take precedence when aliases collide.
+func (b *KubeBuilder) AddSubstitutions(substitutions ...Substitution) {
+	b.Substitutions = append(b.Substitutions, substitutions...)
+}
+
+// Substitute expands substitution aliases in args in place.
+//
+// Aliases are referenced as `%alias`, matching the longest alias at that
+// position, or as `%{alias}` to delimit an alias from following text. `%%`
+// produces a literal `%`, and references that don't resolve are left as-is.
 func (b *KubeBuilder) Substitute(args []string, context, namespace string) []string {
+	generated := make(map[int]generatedSubstitution)
 	for i, arg := range args {
-		for _, sub := range b.Substitutions {
-			for _, alias := range sub.Aliases {
-				substition, err := sub.Generate(context, namespace)
-				if err != nil {
-					continue
-				}
-
-				arg = strings.ReplaceAll(arg, fmt.Sprintf("%%%s", alias), substition)
+		args[i] = b.substituteArg(arg, context, namespace, generated)
+	}
+	return args
+}
+
+type generatedSubstitution struct {
+	value string
+	err   error
+}
+
+func (b *KubeBuilder) substituteArg(arg string, context, namespace string, generated map[int]generatedSubstitution) string {
+	generate := func(idx int) (string, bool) {
+		if idx < 0 {
+			return "", false
+		}
+		res, ok := generated[idx]
+		if !ok {
+			value, err := b.Substitutions[idx].Generate(context, namespace)
+			res = generatedSubstitution{value: value, err: err}
+			generated[idx] = res
+		}
+		return res.value, res.err == nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(arg); {
+		if arg[i] != '%' {
+			sb.WriteByte(arg[i])
+			i++
+			continue
+		}
+
+		rest := arg[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "%"):
+			sb.WriteByte('%')
+			i += 2
+		case strings.HasPrefix(rest, "{"):
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				sb.WriteString(arg[i:])
+				i = len(arg)
+				continue
+			}
+
+			if value, ok := generate(b.exactMatch(rest[1:end])); ok {
+				sb.WriteString(value)
+			} else {
+				sb.WriteString(arg[i : i+end+2])
+			}
+			i += end + 2
+		default:
+			idx, alias := b.longestMatch(rest)
+			if value, ok := generate(idx); ok {
+				sb.WriteString(value)
+				i += len(alias) + 1
+			} else {
+				sb.WriteByte('%')
+				i++
 			}
 		}
+	}
 
-		args[i] = arg
+	return sb.String()
+}
+
+func (b *KubeBuilder) exactMatch(name string) int {
+	for idx, sub := range b.Substitutions {
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return idx
+			}
+		}
 	}
-	return args
+	return -1
+}
+
+func (b *KubeBuilder) longestMatch(s string) (int, string) {
+	match := -1
+	var matchAlias string
+	for idx, sub := range b.Substitutions {
+		for _, alias := range sub.Aliases {
+			if alias != "" && len(alias) > len(matchAlias) && strings.HasPrefix(s, alias) {
+				match = idx
+				matchAlias = alias
+			}
+		}
+	}
+	return match, matchAlias
 }
 
 func (b *KubeBuilder) BuildKubectlArgs(context string, namespace string, allNamespaces bool, assumeClusterAdmin bool, args []string) ([]string, bool) {
@@ -94,9 +183,9 @@ func (b *KubeBuilder) BuildKubectlArgs(context string, namespace string, allName
 
 	var resourceModified bool
 	var resourceType, resourceName string
-	if isResourceModifiableCmd(kubectlCmd) {
+	if isResourceModifiableCmd(kubectlCmd) && len(args) > 1 {
 		resourceType = args[1]
-		if isModifiableResource(resourceType) {
+		if isModifiableResource(resourceType) && len(args) > 2 {
 			resourceName = args[2]
 			resourceModified = true
 		}
@@ -131,11 +220,9 @@ func (b *KubeBuilder) BuildKubectlArgs(context string, namespace string, allName
 		output = append(output, "--as=compute:cluster-admin")
 	}
 
-	if kubectlCmd == "exec" {
-		// if no args passed to exec, assume an interactive shell ie. ` -it -- bash`
-		if len(parsedArgs) == 2 || (resourceModified && len(parsedArgs) == 3) {
-			output = append(output, "-it", "--", "bash")
-		}
+	// if no args passed to exec, assume an interactive shell ie. ` -it -- bash`
+	if b.IsInteractiveExec(parsedArgs) {
+		output = append(output, "-it", "--", b.shell())
 	}
 
 	for idx := last; idx < len(parsedArgs); idx++ {
@@ -145,6 +232,23 @@ func (b *KubeBuilder) BuildKubectlArgs(context string, namespace string, allName
 	return output, confirm
 }
 
+// IsInteractiveExec returns whether args are an exec without a command, which
+// BuildKubectlArgs expands into an interactive shell.
+func (b *KubeBuilder) IsInteractiveExec(args []string) bool {
+	if len(args) == 0 || args[0] != "exec" {
+		return false
+	}
+
+	return len(args) == 2 || (len(args) == 3 && isModifiableResource(args[1]))
+}
+
+func (b *KubeBuilder) shell() string {
+	if b.Shell == "" {
+		return "bash"
+	}
+	return b.Shell
+}
+
 func (b *KubeBuilder) BuildK9sArgs(context string, namespace string, allNamespaces bool, args []string) ([]string, error) {
 	parsedArgs := b.Substitute(args, context, namespace)
 
@@ -1,6 +1,7 @@
 package k8s
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,6 +20,29 @@ func TestSubstitute(t *testing.T) {
 	// default substitution for "n" comes first and wins.
 	firstWinsBuilder := NewKubeBuilderWithSubstitutions([]Substitution{customSub})
 
+	// "z" is a prefix of "zone" and comes first, which would shadow "zone" with
+	// plain string replacement.
+	prefixBuilder := NewKubeBuilderWithSubstitutions([]Substitution{
+		{
+			Aliases: []string{"z"},
+			Generate: func(context, namespace string) (string, error) {
+				return "zz", nil
+			},
+		},
+		{
+			Aliases: []string{"zone"},
+			Generate: func(context, namespace string) (string, error) {
+				return "us-east-1a", nil
+			},
+		},
+		{
+			Aliases: []string{"err"},
+			Generate: func(context, namespace string) (string, error) {
+				return "", errors.New("failed")
+			},
+		},
+	})
+
 	testCases := []struct {
 		name      string
 		builder   KubeBuilder
@@ -67,6 +91,86 @@ func TestSubstitute(t *testing.T) {
 			namespace: "my-namespace",
 			expected:  []string{"get", "pods", "-l", "app=my-namespace-app"},
 		},
+		{
+			name:      "Longest alias wins",
+			builder:   prefixBuilder,
+			args:      []string{"get", "nodes", "-l", "zone=%zone", "-l", "z=%z"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "nodes", "-l", "zone=us-east-1a", "-l", "z=zz"},
+		},
+		{
+			name:      "Longest alias with trailing text",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%nsfoo"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "my-namespacefoo"},
+		},
+		{
+			name:      "Braced alias",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%{n}s", "%{ctx}x"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "my-namespaces", "my-contextx"},
+		},
+		{
+			name:      "Escaped percent",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%%ns", "%%{ctx}", "100%%"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "%ns", "%{ctx}", "100%"},
+		},
+		{
+			name:      "Escaped percent before alias",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%%%ns"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "%my-namespace"},
+		},
+		{
+			name:      "Unmatched percent is literal",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "-o", `go-template={{printf "%d" 1}}`, "100%"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "-o", `go-template={{printf "%d" 1}}`, "100%"},
+		},
+		{
+			name:      "Unknown braced alias is literal",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%{foo}", "%{}"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "%{foo}", "%{}"},
+		},
+		{
+			name:      "Unterminated brace is literal",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%{ns"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "%{ns"},
+		},
+		{
+			name:      "Failed generation is literal",
+			builder:   prefixBuilder,
+			args:      []string{"get", "pods", "%err", "%{err}"},
+			context:   "my-context",
+			namespace: "my-namespace",
+			expected:  []string{"get", "pods", "%err", "%{err}"},
+		},
+		{
+			name:      "Substituted values are not expanded",
+			builder:   defaultBuilder,
+			args:      []string{"get", "pods", "%ns"},
+			context:   "my-context",
+			namespace: "%ctx",
+			expected:  []string{"get", "pods", "%ctx"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -116,6 +220,11 @@ func TestBuildKubectlArgs(t *testing.T) {
 			expectedArgs:       []string{"edit", "deployment", "my-deployment", "--as=compute:cluster-admin"},
 			expectedConfirm:    false,
 		},
+		{
+			name:         "Incomplete resource command",
+			args:         []string{"logs", "deploy"},
+			expectedArgs: []string{"logs", "deploy"},
+		},
 		{
 			name:            "Confirmation for mutating commands",
 			args:            []string{"delete", "pod", "my-pod"},
@@ -253,3 +362,58 @@ func TestBuildK9sArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestIsInteractiveExec(t *testing.T) {
+	builder := NewKubeBuilder()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected bool
+	}{
+		{
+			name:     "Empty exec",
+			args:     []string{"exec", "my-pod"},
+			expected: true,
+		},
+		{
+			name:     "Empty exec for rewritable resource",
+			args:     []string{"exec", "deploy", "my-deploy"},
+			expected: true,
+		},
+		{
+			name:     "Exec with command",
+			args:     []string{"exec", "my-pod", "--", "ls"},
+			expected: false,
+		},
+		{
+			name:     "Exec with flags",
+			args:     []string{"exec", "my-pod", "-c"},
+			expected: false,
+		},
+		{
+			name:     "Not exec",
+			args:     []string{"logs", "my-pod"},
+			expected: false,
+		},
+		{
+			name:     "Empty arguments",
+			args:     []string{},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, builder.IsInteractiveExec(tc.args))
+		})
+	}
+}
+
+func TestBuildKubectlArgsShell(t *testing.T) {
+	builder := NewKubeBuilder()
+	builder.Shell = "sh"
+
+	result, _ := builder.BuildKubectlArgs("", "", false, false, []string{"exec", "my-pod"})
+	assert.Equal(t, []string{"exec", "my-pod", "-it", "--", "sh"}, result)
+}
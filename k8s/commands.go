@@ -3,10 +3,13 @@ package k8s
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/bitfield/script"
 	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/michaelmdeng/mdcli/internal/config"
 	"github.com/urfave/cli/v2"
 )
 
@@ -54,6 +57,7 @@ var BaseK8sFlags = []cli.Flag{
 		Value:   false,
 		Usage:   "Run command across all namespaces",
 	},
+	RecentFlag,
 }
 
 var BaseKctlFlags = []cli.Flag{
@@ -69,6 +73,27 @@ var BaseKctlFlags = []cli.Flag{
 		Value:   false,
 		Usage:   "Assume cluster-admin role for port-forward",
 	},
+	&cli.StringFlag{
+		Name:  "shell",
+		Value: "",
+		Usage: "`SHELL` to start for exec without a command. Detected from the container if not provided",
+	},
+}
+
+var FanoutFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "contexts",
+		Usage: "Run the command across multiple `CONTEXTS`, ie. a,b,c. Supports globs",
+	},
+	&cli.StringSliceFlag{
+		Name:  "namespaces",
+		Usage: "Run the command across multiple `NAMESPACES`, ie. tidb-mussel-prod*. Supports globs",
+	},
+	&cli.IntFlag{
+		Name:  "parallelism",
+		Value: 4,
+		Usage: "Maximum number of contexts and namespaces to run against concurrently",
+	},
 }
 
 func BaseCommand() *cli.Command {
@@ -78,7 +103,18 @@ func BaseCommand() *cli.Command {
 		Usage:   k8sUsage,
 		Subcommands: []*cli.Command{
 			kubectlCommand(),
+			shellCommand(),
 			k9sCommand(),
+			portForwardCommand(),
+			logsCommand(),
+			statusCommand(),
+			nodeCommand(),
+			contextsCommand(),
+			useCommand(),
+			currentCommand(),
+			secretCommand(),
+			dumpCommand(),
+			k9sConfigCommand(),
 		},
 	}
 }
@@ -88,8 +124,10 @@ func kubectlCommand() *cli.Command {
 		Name:    "kubectl",
 		Aliases: []string{"kc", "kctl"},
 		Usage:   "Custom kubectl wrapper",
-		Flags:   BaseK8sFlags,
+		Flags:   append(append(append(BaseK8sFlags, BaseKctlFlags...), FanoutFlags...), JqFlags...),
 		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
 			namespace := cCtx.String("namespace")
@@ -97,25 +135,97 @@ func kubectlCommand() *cli.Command {
 			dryRun := cCtx.Bool("dryrun")
 			allNamespaces := cCtx.Bool("all-namespaces")
 			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
+			confirmed := cCtx.Bool("yes")
+			shell := cCtx.String("shell")
+			debug := cCtx.Bool("debug")
+			fanoutContexts := cCtx.StringSlice("contexts")
+			fanoutNamespaces := cCtx.StringSlice("namespaces")
 
-			var err error
-			context, err = ParseContext(context, interactive, "", strict)
+			jq, err := ParseJqFlags(cCtx)
 			if err != nil {
 				return err
 			}
+			kubectlArgs := cCtx.Args().Slice()
+			if jq != nil {
+				kubectlArgs = WithJSONOutput(kubectlArgs)
+			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "", strict)
-			if err != nil {
-				return err
+			var contextNote, namespaceNote string
+			if len(fanoutContexts) == 0 {
+				context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+				if err != nil {
+					return err
+				}
+
+				context, contextNote, err = ParseContext(context, interactive, "", strict)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(fanoutNamespaces) == 0 {
+				namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "", strict)
+				if err != nil {
+					return err
+				}
 			}
 
 			builder := NewKubeBuilder()
-			args, confirm := builder.BuildKubectlArgs(context, namespace, allNamespaces, assumeClusterAdmin, cCtx.Args().Slice())
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+
+			if len(fanoutContexts) > 0 || len(fanoutNamespaces) > 0 {
+				if len(fanoutContexts) == 0 {
+					fanoutContexts = []string{context}
+				}
+				if len(fanoutNamespaces) == 0 {
+					fanoutNamespaces = []string{namespace}
+				}
+
+				targets, err := FanoutTargets(fanoutContexts, fanoutNamespaces, allNamespaces, nil)
+				if err != nil {
+					return err
+				}
+
+				return builder.RunKubectlFanout(targets, kubectlArgs, FanoutOptions{
+					Parallelism: cCtx.Int("parallelism"),
+					Jq:          jq,
+					Confirmed:   confirmed,
+					Debug:       debug,
+					AssumeClusterAdmin: func(string) bool {
+						return assumeClusterAdmin
+					},
+				})
+			}
+
+			builder.Shell = shell
+			if builder.Shell == "" && builder.IsInteractiveExec(cCtx.Args().Slice()) {
+				if dryRun {
+					// Don't probe the pod on a dry run
+					if len(cfg.K8s.Shells) > 0 {
+						builder.Shell = cfg.K8s.Shells[0]
+					}
+				} else {
+					builder.Shell, err = builder.ResolveShell(context, namespace, assumeClusterAdmin, cCtx.Args().Slice(), cfg.K8s.Shells)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			args, confirm := builder.BuildKubectlArgs(context, namespace, allNamespaces, assumeClusterAdmin, kubectlArgs)
 			if dryRun {
 				fmt.Printf("%s %s\n", Kubectl, strings.Join(args, " "))
 				return nil
-			} else if confirm {
+			} else if debug || (confirm && !confirmed) {
+				if note := StickyUsage(contextNote, namespaceNote); debug && note != "" {
+					fmt.Printf("Using %s\n", note)
+				}
 				fmt.Printf("%s %s\n", Kubectl, strings.Join(args, " "))
+			}
+
+			if confirm && !confirmed {
 				res := mdexec.GetConfirmation("Do you want to execute the above command?")
 				if !res {
 					fmt.Println("Command canceled")
@@ -123,6 +233,204 @@ func kubectlCommand() *cli.Command {
 				}
 			}
 
+			if jq != nil {
+				return RunKubectlJq(args, jq)
+			}
+			return mdexec.RunCommand(Kubectl, args...)
+		},
+	}
+}
+
+func shellCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "shell",
+		Usage: "Interactive kubectl shell for a context and namespace",
+		Flags: append(BaseK8sFlags, BaseKctlFlags...),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+
+			if note := StickyUsage(contextNote, namespaceNote); cCtx.Bool("debug") && note != "" {
+				fmt.Printf("Using %s\n", note)
+			}
+			return builder.RunRepl(ReplOptions{
+				Context:   context,
+				Namespace: namespace,
+				Confirmed: cCtx.Bool("yes"),
+				Debug:     cCtx.Bool("debug"),
+				Shell:     cCtx.String("shell"),
+				Shells:    cfg.K8s.Shells,
+				AssumeClusterAdmin: func(string) bool {
+					return assumeClusterAdmin
+				},
+			})
+		},
+	}
+}
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Summarise the health of a namespace. Exits non-zero if unhealthy",
+		Flags: BaseK8sFlags,
+		Action: func(cCtx *cli.Context) error {
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			allNamespaces := cCtx.Bool("all-namespaces")
+			debug := cCtx.Bool("debug")
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			if note := StickyUsage(contextNote, namespaceNote); debug && note != "" {
+				fmt.Printf("Using %s\n", note)
+			}
+
+			builder := NewKubeBuilder()
+			status, err := builder.NamespaceStatus(StatusOptions{
+				Context:       context,
+				Namespace:     namespace,
+				AllNamespaces: allNamespaces,
+				Debug:         debug,
+				DebugPrintf: func(context string, format string, args ...any) {
+					fmt.Printf(format+"\n", args...)
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			status.Print(os.Stdout, allNamespaces)
+			if !status.Healthy() {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
+func nodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "node",
+		Usage:     "List the pods on a node, or start a debug shell on it",
+		ArgsUsage: "<name|instance-id>",
+		Flags: append(BaseK8sFlags,
+			&cli.BoolFlag{
+				Name:  "shell",
+				Value: false,
+				Usage: "Start a privileged debug pod on the node instead of listing its pods",
+			},
+			&cli.StringFlag{
+				Name:  "image",
+				Value: DefaultNodeDebugImage,
+				Usage: "`IMAGE` of the debug pod started by --shell",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Value:   false,
+				Usage:   "Automatic yes to the confirmation prompt for --shell",
+			},
+			&cli.BoolFlag{
+				Name:    "assume-cluster-admin",
+				Aliases: []string{"cluster-admin"},
+				Value:   false,
+				Usage:   "Assume cluster-admin role for --shell",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			// Only used for the debug pod, which defaults to the kubeconfig namespace
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			debug := cCtx.Bool("debug")
+
+			if cCtx.NArg() != 1 {
+				return cli.Exit("a node name or instance id must be provided", 1)
+			}
+
+			var err error
+			context, _, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			node, err := builder.ResolveNode(context, cCtx.Args().First(), cfg.K8s.InstanceIdLabel)
+			if err != nil {
+				return err
+			}
+
+			if !cCtx.Bool("shell") {
+				pods, err := builder.NodePods(context, node)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Node %s\n", node)
+				PrintNodePods(os.Stdout, pods)
+				return nil
+			}
+
+			args, _ := builder.BuildKubectlArgs(context, namespace, false, false, NodeDebugArgs(node, cCtx.String("image"), cCtx.Bool("assume-cluster-admin")))
+			needsConfirm := !cCtx.Bool("yes")
+			if debug || needsConfirm {
+				fmt.Printf("%s %s\n", Kubectl, strings.Join(args, " "))
+			}
+			if needsConfirm {
+				if !mdexec.GetConfirmation("Do you want to start a privileged pod on the node?") {
+					fmt.Println("Command canceled")
+					return errors.New("command canceled")
+				}
+			}
+
+			fmt.Println("The host filesystem is mounted at /host, run `chroot /host` for a host shell")
 			return mdexec.RunCommand(Kubectl, args...)
 		},
 	}
@@ -134,6 +442,8 @@ func k9sCommand() *cli.Command {
 		Usage: "Custom k9s wrapper",
 		Flags: BaseK8sFlags,
 		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
 			namespace := cCtx.String("namespace")
@@ -142,17 +452,26 @@ func k9sCommand() *cli.Command {
 			allNamespaces := cCtx.Bool("all-namespaces")
 
 			var err error
-			context, err = ParseContext(context, interactive, "", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
 			if err != nil {
 				return err
 			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "", strict)
+			context, contextNote, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "", strict)
 			if err != nil {
 				return err
 			}
 
 			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
 			args, err := builder.BuildK9sArgs(context, namespace, allNamespaces, cCtx.Args().Slice())
 			if err != nil {
 				return err
@@ -161,6 +480,17 @@ func k9sCommand() *cli.Command {
 			if dryRun {
 				fmt.Printf("%s %s\n", K9s, strings.Join(args, " "))
 				return nil
+			} else if note := StickyUsage(contextNote, namespaceNote); cCtx.Bool("debug") && note != "" {
+				fmt.Printf("Using %s\n", note)
+			}
+
+			if dir, ok := K9sConfigDir(context); ok {
+				if cCtx.Bool("debug") {
+					fmt.Printf("Using k9s config %s\n", dir)
+				}
+				if err := os.Setenv("K9S_CONFIG_DIR", dir); err != nil {
+					return err
+				}
 			}
 
 			_, err = script.Exec(fmt.Sprintf("%s %s", K9s, strings.Join(args, " "))).Stdout()
@@ -168,3 +498,257 @@ func k9sCommand() *cli.Command {
 		},
 	}
 }
+
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "logs",
+		Aliases:   []string{"log"},
+		Usage:     "Tail logs from multiple pods and containers",
+		ArgsUsage: "[pod-regex]",
+		Flags:     append(append(BaseK8sFlags, BaseKctlFlags...), LogsFlags...),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+
+			opts, err := NewLogsOptions(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			var contextNote, namespaceNote string
+			context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+
+			if note := StickyUsage(contextNote, namespaceNote); opts.Debug && note != "" {
+				fmt.Fprintf(os.Stderr, "Using %s\n", note)
+			}
+
+			opts.Context = context
+			opts.Namespace = namespace
+			return builder.TailLogs(opts)
+		},
+	}
+}
+
+func secretCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "secret",
+		Usage:     "Decode a secret, listing its keys if no key is provided",
+		ArgsUsage: "<name> [key]",
+		Flags: append(BaseK8sFlags,
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Output `FORMAT` for the secret, one of env, dotenv or json",
+			},
+			&cli.BoolFlag{
+				Name:  "copy",
+				Value: false,
+				Usage: "Copy the output to the clipboard instead of printing it",
+			},
+			&cli.BoolFlag{
+				Name:    "assume-cluster-admin",
+				Aliases: []string{"cluster-admin"},
+				Value:   false,
+				Usage:   "Assume cluster-admin role to read the secret",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+
+			if cCtx.NArg() < 1 || cCtx.NArg() > 2 {
+				return cli.Exit("a secret name and optional key must be provided", 1)
+			}
+			name := cCtx.Args().Get(0)
+			key := cCtx.Args().Get(1)
+
+			context, namespace, err := ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, contextNote, err := ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			var namespaceNote string
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+			name = builder.Substitute([]string{name}, context, namespace)[0]
+			if note := StickyUsage(contextNote, namespaceNote); cCtx.Bool("debug") && note != "" {
+				fmt.Fprintf(os.Stderr, "Using %s\n", note)
+			}
+
+			data, err := builder.GetSecret(context, namespace, name, cCtx.Bool("assume-cluster-admin"))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			output, err := FormatSecret(data, key, cCtx.String("output"))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if cCtx.Bool("copy") {
+				if err := CopyToClipboard(cfg.K8s.ClipboardCommand, output); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				fmt.Fprintf(os.Stderr, "Copied %s to clipboard\n", name)
+				return nil
+			}
+
+			fmt.Print(output)
+			return nil
+		},
+	}
+}
+
+func dumpCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "dump",
+		Usage:     "Export the resources in a namespace to a <kind>/<name>.yaml tree",
+		ArgsUsage: "[dir]",
+		Flags: append(BaseK8sFlags,
+			&cli.StringSliceFlag{
+				Name:  "kinds",
+				Value: cli.NewStringSlice(DefaultDumpKinds...),
+				Usage: "Resource `KINDS` to export",
+			},
+			&cli.StringFlag{
+				Name:  "diff",
+				Value: "",
+				Usage: "Compare the live state against a previous dump in `DIR` instead of exporting",
+			},
+			&cli.BoolFlag{
+				Name:    "assume-cluster-admin",
+				Aliases: []string{"cluster-admin"},
+				Value:   false,
+				Usage:   "Assume cluster-admin role to read resources",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+
+			context, namespace, err := ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, contextNote, err := ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			var namespaceNote string
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+
+			opts := DumpOptions{
+				Context:            context,
+				Namespace:          namespace,
+				Kinds:              cCtx.StringSlice("kinds"),
+				AssumeClusterAdmin: cCtx.Bool("assume-cluster-admin"),
+				Debug:              cCtx.Bool("debug"),
+			}
+			if note := StickyUsage(contextNote, namespaceNote); opts.Debug && note != "" {
+				fmt.Fprintf(os.Stderr, "Using %s\n", note)
+			}
+
+			if diffDir := cCtx.String("diff"); diffDir != "" {
+				return builder.DiffDump(opts, diffDir)
+			}
+
+			dir := cCtx.Args().First()
+			if dir == "" {
+				dir = fmt.Sprintf("%s-%s", namespace, time.Now().Format("20060102-150405"))
+			}
+			if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+				return cli.Exit(fmt.Sprintf("%s already exists and is not empty", dir), 1)
+			}
+
+			count, err := builder.DumpNamespace(opts, dir, os.Stderr)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Printf("Exported %d resources to %s\n", count, dir)
+			return nil
+		},
+	}
+}
+
+func k9sConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "k9s-config",
+		Usage: "Manage the k9s config used by the k9s wrappers",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "Generate k9s config dirs with per-environment skins and mdcli plugins",
+				Action: func(cCtx *cli.Context) error {
+					executable, err := os.Executable()
+					if err != nil {
+						return err
+					}
+
+					dirs, err := GenerateK9sConfig(executable)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					for _, dir := range dirs {
+						fmt.Printf("Generated %s\n", dir)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,278 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/urfave/cli/v2"
+)
+
+var kubeconfigFlag = &cli.StringFlag{
+	Name:  "kubeconfig",
+	Value: "",
+	Usage: "`PATH` of the kubeconfig to edit. Defaults to the first file in KUBECONFIG or ~/.kube/config",
+}
+
+var contextsYesFlag = &cli.BoolFlag{
+	Name:    "yes",
+	Aliases: []string{"y"},
+	Value:   false,
+	Usage:   "Automatic yes to confirmation prompts",
+}
+
+func contextsCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "contexts",
+		Aliases: []string{"ctx"},
+		Usage:   "Manage kubeconfig contexts. Edits back up the kubeconfig to <kubeconfig>.bak",
+		Subcommands: []*cli.Command{
+			contextsListCommand(),
+			contextsRenameCommand(),
+			contextsRemoveCommand(),
+			contextsPruneCommand(),
+			contextsMergeCommand(),
+		},
+	}
+}
+
+// loadKubeconfig loads the kubeconfig from the --kubeconfig flag, returning it
+// with its path.
+func loadKubeconfig(cCtx *cli.Context) (*Kubeconfig, string, error) {
+	path, err := KubeconfigPath(cCtx.String("kubeconfig"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	k, err := LoadKubeconfig(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return k, path, nil
+}
+
+func contextsListCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "ls",
+		Aliases:   []string{"list"},
+		Usage:     "List contexts with their cluster and whether it is reachable",
+		ArgsUsage: "[pattern]",
+		Flags: []cli.Flag{
+			kubeconfigFlag,
+			&cli.BoolFlag{
+				Name:  "check",
+				Value: true,
+				Usage: "Check whether each cluster server is reachable",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 2 * time.Second,
+				Usage: "`TIMEOUT` for the reachability check",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			k, _, err := loadKubeconfig(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			check := cCtx.Bool("check")
+			statuses, err := k.ContextStatuses(cCtx.Args().First(), check, cCtx.Duration("timeout"))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			header := "CURRENT\tNAME\tCLUSTER\tSERVER"
+			if check {
+				header += "\tREACHABLE"
+			}
+			fmt.Fprintln(w, header)
+			for _, status := range statuses {
+				current := ""
+				if status.Current {
+					current = "*"
+				}
+				server := status.Server
+				if server == "" {
+					server = "<missing>"
+				}
+
+				line := fmt.Sprintf("%s\t%s\t%s\t%s", current, status.Name, status.Cluster, server)
+				if check {
+					if status.Reachable {
+						line += "\t" + color.GreenString("yes")
+					} else {
+						line += "\t" + color.RedString("no")
+					}
+				}
+				fmt.Fprintln(w, line)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func contextsRenameCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "rename",
+		Aliases:   []string{"mv"},
+		Usage:     "Rename a context",
+		ArgsUsage: "<context> <new-name>",
+		Flags:     []cli.Flag{kubeconfigFlag},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() != 2 {
+				return cli.Exit("a context and its new name must be provided", 1)
+			}
+
+			k, path, err := loadKubeconfig(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			from, to := cCtx.Args().Get(0), cCtx.Args().Get(1)
+			if err := k.RenameContext(from, to); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if err := k.Save(path); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Printf("Renamed context %s to %s\n", from, to)
+			return nil
+		},
+	}
+}
+
+// confirmRemoval lists the entries and asks for confirmation unless confirmed.
+func confirmRemoval(entries []string, confirmed bool) bool {
+	fmt.Println("Removing:")
+	for _, entry := range entries {
+		fmt.Printf("  %s\n", entry)
+	}
+	return confirmed || mdexec.GetConfirmation("Do you want to remove the above entries?")
+}
+
+func contextsRemoveCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "rm",
+		Aliases:   []string{"remove", "delete"},
+		Usage:     "Remove contexts and their clusters and users if no other context uses them",
+		ArgsUsage: "<context>...",
+		Flags:     []cli.Flag{kubeconfigFlag, contextsYesFlag},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() == 0 {
+				return cli.Exit("a context must be provided", 1)
+			}
+
+			k, path, err := loadKubeconfig(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			removed := make([]string, 0)
+			for _, name := range cCtx.Args().Slice() {
+				entries, err := k.RemoveContext(name)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				removed = append(removed, entries...)
+			}
+
+			if !confirmRemoval(removed, cCtx.Bool("yes")) {
+				return cli.Exit("Command canceled by user", 1)
+			}
+			if err := k.Save(path); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			return nil
+		},
+	}
+}
+
+func contextsPruneCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "Remove contexts whose cluster is missing from the kubeconfig",
+		Flags: []cli.Flag{kubeconfigFlag, contextsYesFlag},
+		Action: func(cCtx *cli.Context) error {
+			k, path, err := loadKubeconfig(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			dangling := k.DanglingContexts()
+			if len(dangling) == 0 {
+				fmt.Println("No contexts to prune")
+				return nil
+			}
+
+			removed := make([]string, 0)
+			for _, name := range dangling {
+				entries, err := k.RemoveContext(name)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				removed = append(removed, entries...)
+			}
+
+			if !confirmRemoval(removed, cCtx.Bool("yes")) {
+				return cli.Exit("Command canceled by user", 1)
+			}
+			if err := k.Save(path); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			return nil
+		},
+	}
+}
+
+func contextsMergeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "merge",
+		Usage:     "Merge the clusters, users and contexts of another kubeconfig",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			kubeconfigFlag,
+			&cli.BoolFlag{
+				Name:  "overwrite",
+				Value: false,
+				Usage: "Replace entries which already exist instead of skipping them",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() != 1 {
+				return cli.Exit("a kubeconfig file to merge must be provided", 1)
+			}
+
+			k, path, err := loadKubeconfig(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			other, err := LoadKubeconfig(cCtx.Args().First())
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			result := k.Merge(other, cCtx.Bool("overwrite"))
+			if len(result.Skipped) > 0 {
+				fmt.Printf("Skipped existing entries, use --overwrite to replace them: %s\n", strings.Join(result.Skipped, ", "))
+			}
+			if len(result.Added) == 0 {
+				fmt.Println("Nothing to merge")
+				return nil
+			}
+
+			if err := k.Save(path); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			fmt.Printf("Merged %s\n", strings.Join(result.Added, ", "))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,228 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDumpKinds are the resource kinds exported by `mdcli k8s dump` unless
+// overridden. Secrets are deliberately excluded.
+var DefaultDumpKinds = []string{
+	"deployments",
+	"statefulsets",
+	"daemonsets",
+	"cronjobs",
+	"services",
+	"configmaps",
+	"serviceaccounts",
+	"roles",
+	"rolebindings",
+	"poddisruptionbudgets",
+	"ingresses",
+	"tidbclusters",
+}
+
+// strippedMetadata are the server-populated metadata fields removed from
+// dumped resources.
+var strippedMetadata = []string{
+	"managedFields",
+	"resourceVersion",
+	"uid",
+	"creationTimestamp",
+}
+
+// DumpOptions configures DumpNamespace.
+type DumpOptions struct {
+	Context            string
+	Namespace          string
+	Kinds              []string
+	AssumeClusterAdmin bool
+	Debug              bool
+	DebugPrintf        func(context string, format string, args ...any)
+}
+
+// normalizeJSON converts json.Number values decoded with UseNumber to int64 or
+// float64 so they marshal to YAML as numbers.
+func normalizeJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalizeJSON(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJSON(item)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// cleanResource strips status and server-populated metadata from resource.
+func cleanResource(resource map[string]any) {
+	delete(resource, "status")
+
+	if metadata, ok := resource["metadata"].(map[string]any); ok {
+		for _, field := range strippedMetadata {
+			delete(metadata, field)
+		}
+	}
+}
+
+// dumpPath is the path of the resource within a dump, ie.
+// deployment/foo.yaml.
+func dumpPath(resource map[string]any) (string, error) {
+	kind, _ := resource["kind"].(string)
+	metadata, _ := resource["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+	if kind == "" || name == "" {
+		return "", errors.New("resource is missing kind or name")
+	}
+	return filepath.Join(strings.ToLower(kind), name+".yaml"), nil
+}
+
+// parseResourceList decodes the items of a kubectl -o json list.
+func parseResourceList(data []byte) ([]map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var list struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := decoder.Decode(&list); err != nil {
+		return nil, err
+	}
+
+	for _, item := range list.Items {
+		normalizeJSON(item)
+	}
+	return list.Items, nil
+}
+
+func writeResource(dir string, resource map[string]any) (string, error) {
+	cleanResource(resource)
+
+	path, err := dumpPath(resource)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(resource); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", err
+	}
+	return path, state.WriteFileAtomic(fullPath, buf.Bytes(), 0644)
+}
+
+// DumpNamespace exports the resources of each kind in the namespace to
+// <kind>/<name>.yaml under dir. Kinds that don't exist in the cluster, ie.
+// CRDs that aren't installed, are skipped with a warning. Any other failure is
+// returned, so a dump is never silently incomplete.
+func (b *KubeBuilder) DumpNamespace(opts DumpOptions, dir string, stderr io.Writer) (int, error) {
+	debugPrintf := opts.DebugPrintf
+	if debugPrintf == nil {
+		debugPrintf = func(context string, format string, args ...any) {
+			fmt.Fprintf(stderr, format+"\n", args...)
+		}
+	}
+
+	count := 0
+	for _, kind := range opts.Kinds {
+		kubectlArgs, _ := b.BuildKubectlArgs(opts.Context, opts.Namespace, false, opts.AssumeClusterAdmin, []string{"get", kind, "-o", "json"})
+		if opts.Debug {
+			debugPrintf(opts.Context, "%s %s", Kubectl, strings.Join(kubectlArgs, " "))
+		}
+
+		var errOut bytes.Buffer
+		c := exec.Command(Kubectl, kubectlArgs...)
+		c.Stderr = &errOut
+		output, err := c.Output()
+		if err != nil {
+			reason := strings.TrimSpace(errOut.String())
+			if isMissingResourceType(reason) {
+				fmt.Fprintf(stderr, "Warning: skipping %s: %s\n", kind, reason)
+				continue
+			}
+			if reason == "" {
+				reason = err.Error()
+			}
+			return count, fmt.Errorf("failed to get %s: %s", kind, reason)
+		}
+
+		resources, err := parseResourceList(output)
+		if err != nil {
+			return count, fmt.Errorf("failed to parse %s: %w", kind, err)
+		}
+
+		for _, resource := range resources {
+			if _, err := writeResource(dir, resource); err != nil {
+				return count, fmt.Errorf("failed to write %s: %w", kind, err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// isMissingResourceType returns whether kubectl failed because the cluster
+// doesn't know the kind.
+func isMissingResourceType(stderr string) bool {
+	return strings.Contains(stderr, "doesn't have a resource type")
+}
+
+// DiffDump dumps the live namespace to a temporary directory and diffs a
+// previous dump in dir against it.
+func (b *KubeBuilder) DiffDump(opts DumpOptions, dir string) error {
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	live, err := os.MkdirTemp("", "mdcli-dump-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(live)
+	}()
+
+	if _, err := b.DumpNamespace(opts, live, os.Stderr); err != nil {
+		return err
+	}
+
+	c := exec.Command("diff", "-ru", dir, live)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteResource(t *testing.T) {
+	data := []byte(`{"items":[{
+		"apiVersion": "apps/v1",
+		"kind": "StatefulSet",
+		"metadata": {
+			"name": "foo",
+			"namespace": "bar",
+			"uid": "1234",
+			"resourceVersion": "5678",
+			"creationTimestamp": "2025-01-01T00:00:00Z",
+			"managedFields": [{"manager": "kubectl"}],
+			"labels": {"app": "foo"}
+		},
+		"spec": {"replicas": 3, "ratio": 0.5},
+		"status": {"readyReplicas": 3}
+	}]}`)
+
+	resources, err := parseResourceList(data)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+
+	dir := t.TempDir()
+	path, err := writeResource(dir, resources[0])
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("statefulset", "foo.yaml"), path)
+
+	out, err := os.ReadFile(filepath.Join(dir, path))
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  labels:
+    app: foo
+  name: foo
+  namespace: bar
+spec:
+  ratio: 0.5
+  replicas: 3
+`, string(out))
+}
+
+func TestDumpPathMissingName(t *testing.T) {
+	_, err := dumpPath(map[string]any{"kind": "Service"})
+	assert.Error(t, err)
+}
+
+func TestIsMissingResourceType(t *testing.T) {
+	assert.True(t, isMissingResourceType(`error: the server doesn't have a resource type "tidbclusters"`))
+	assert.False(t, isMissingResourceType(`Error from server (Forbidden): secrets is forbidden: User "me" cannot list resource "secrets"`))
+	assert.False(t, isMissingResourceType("Unable to connect to the server: dial tcp: i/o timeout"))
+}
@@ -0,0 +1,375 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+)
+
+var prefixColors = []color.Attribute{
+	color.FgCyan,
+	color.FgGreen,
+	color.FgYellow,
+	color.FgBlue,
+	color.FgMagenta,
+	color.FgHiCyan,
+	color.FgHiGreen,
+	color.FgHiYellow,
+	color.FgHiBlue,
+	color.FgHiMagenta,
+}
+
+// Target is a single context and namespace a command is run against. An empty
+// namespace with AllNamespaces targets every namespace in the context.
+type Target struct {
+	Context       string
+	Namespace     string
+	AllNamespaces bool
+}
+
+func (t Target) String() string {
+	namespace := t.Namespace
+	if t.AllNamespaces {
+		namespace = "*"
+	}
+	return fmt.Sprintf("%s/%s", t.Context, namespace)
+}
+
+// FanoutError is returned when a command fails for some of the fanned-out
+// targets. It implements cli.ExitCoder with the highest exit code.
+type FanoutError struct {
+	Failed   []Target
+	exitCode int
+}
+
+func (e *FanoutError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for _, t := range e.Failed {
+		failed = append(failed, t.String())
+	}
+	return fmt.Sprintf("command failed for %s", strings.Join(failed, ", "))
+}
+
+func (e *FanoutError) ExitCode() int {
+	return e.exitCode
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// matchNames returns the names matching any of patterns, in the order of names.
+func matchNames(names []string, patterns []string) ([]string, error) {
+	matches := make([]string, 0)
+	for _, name := range names {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				matches = append(matches, name)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func listContexts() ([]string, error) {
+	output, err := mdexec.CaptureCommand(Kubectl, "config", "get-contexts", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+func listNamespaces(context string) ([]string, error) {
+	args := make([]string, 0)
+	if context != "" {
+		args = append(args, "--context", context)
+	}
+	args = append(args, "get", "ns", "-o", "jsonpath={.items[*].metadata.name}")
+	output, err := mdexec.CaptureCommand(Kubectl, args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// FanoutTargets expands contexts and namespaces into the targets to run
+// against. Both support globs, which are matched against the contexts in the
+// kubeconfig and the namespaces in each context respectively.
+//
+// resolve, if set, is applied to each context and namespace pattern pair before
+// namespace globs are expanded, so callers can map aliases to full names.
+func FanoutTargets(contexts []string, namespaces []string, allNamespaces bool, resolve func(context, namespace string) (string, string)) ([]Target, error) {
+	if len(contexts) == 0 {
+		contexts = []string{""}
+	}
+	if len(namespaces) == 0 || allNamespaces {
+		namespaces = []string{""}
+	}
+
+	expandedContexts := make([]string, 0)
+	var kubeContexts []string
+	for _, context := range contexts {
+		if !isGlob(context) {
+			expandedContexts = append(expandedContexts, context)
+			continue
+		}
+
+		if kubeContexts == nil {
+			var err error
+			kubeContexts, err = listContexts()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list contexts: %w", err)
+			}
+		}
+		matches, err := matchNames(kubeContexts, []string{context})
+		if err != nil {
+			return nil, err
+		}
+		expandedContexts = append(expandedContexts, matches...)
+	}
+
+	targets := make([]Target, 0)
+	for _, context := range expandedContexts {
+		var contextNamespaces []string
+		for _, namespace := range namespaces {
+			resolvedContext, resolvedNamespace := context, namespace
+			if resolve != nil {
+				resolvedContext, resolvedNamespace = resolve(context, namespace)
+			}
+
+			if !isGlob(resolvedNamespace) {
+				targets = append(targets, Target{
+					Context:       resolvedContext,
+					Namespace:     resolvedNamespace,
+					AllNamespaces: allNamespaces,
+				})
+				continue
+			}
+
+			if contextNamespaces == nil {
+				var err error
+				contextNamespaces, err = listNamespaces(resolvedContext)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list namespaces in %s: %w", resolvedContext, err)
+				}
+			}
+			matches, err := matchNames(contextNamespaces, []string{resolvedNamespace})
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				targets = append(targets, Target{Context: resolvedContext, Namespace: match})
+			}
+		}
+	}
+
+	deduped := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		if !slices.Contains(deduped, target) {
+			deduped = append(deduped, target)
+		}
+	}
+
+	if len(deduped) == 0 {
+		return nil, errors.New("no contexts or namespaces matched")
+	}
+	return deduped, nil
+}
+
+// prefixWriter writes each complete line to w with a prefix, serializing writes
+// from concurrent writers through mu. Lines rejected by filter, if set, are
+// dropped.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	filter func(line []byte) bool
+	buf    []byte
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{mu: mu, w: w, prefix: prefix}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		idx := bytes.IndexByte(p.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		if err := p.writeLine(p.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[idx+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any trailing partial line.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	if p.filter != nil && !p.filter(line) {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := io.WriteString(p.w, p.prefix); err != nil {
+		return err
+	}
+	_, err := p.w.Write(line)
+	return err
+}
+
+func targetPrefix(target Target, idx int, width int) string {
+	label := fmt.Sprintf("%-*s", width, target.String())
+	return color.New(prefixColors[idx%len(prefixColors)]).Sprint(label) + " | "
+}
+
+// RunFanout runs fn against each target, at most parallelism at a time. Each
+// line written to stdout and stderr is prefixed with the coloured target.
+func RunFanout(targets []Target, parallelism int, stdout, stderr io.Writer, fn func(target Target, stdout, stderr io.Writer) error) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	width := 0
+	for _, target := range targets {
+		width = max(width, len(target.String()))
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	errs := make([]error, len(targets))
+	for idx, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			prefix := targetPrefix(target, idx, width)
+			outWriter := newPrefixWriter(stdout, &mu, prefix)
+			errWriter := newPrefixWriter(stderr, &mu, prefix)
+			errs[idx] = fn(target, outWriter, errWriter)
+			_ = outWriter.Flush()
+			_ = errWriter.Flush()
+		}()
+	}
+	wg.Wait()
+
+	fanoutErr := &FanoutError{}
+	for idx, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		exitCode := 1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			_, _ = fmt.Fprintf(stderr, "%s%v\n", targetPrefix(targets[idx], idx, width), err)
+		}
+		fanoutErr.Failed = append(fanoutErr.Failed, targets[idx])
+		fanoutErr.exitCode = max(fanoutErr.exitCode, exitCode)
+	}
+
+	if len(fanoutErr.Failed) > 0 {
+		return fanoutErr
+	}
+	return nil
+}
+
+// FanoutOptions configures RunKubectlFanout.
+type FanoutOptions struct {
+	Parallelism int
+	// Skip the confirmation prompt for confirmable commands
+	Confirmed bool
+	Debug     bool
+	// Filter the output of each target, if set
+	Jq *JqFilter
+	// AssumeClusterAdmin returns whether to impersonate cluster-admin in context
+	AssumeClusterAdmin func(context string) bool
+	// DebugPrintf prints the plan and debug output, defaults to stderr
+	DebugPrintf func(context string, format string, args ...any)
+}
+
+// RunKubectlFanout builds and runs the kubectl args against each target
+// concurrently. Confirmable commands print the full plan and prompt once.
+func (b *KubeBuilder) RunKubectlFanout(targets []Target, args []string, opts FanoutOptions) error {
+	if b.IsInteractiveExec(args) {
+		return errors.New("interactive exec cannot be run across multiple targets")
+	}
+
+	debugPrintf := opts.DebugPrintf
+	if debugPrintf == nil {
+		debugPrintf = func(context string, format string, args ...any) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	kubectlArgs := make(map[Target][]string, len(targets))
+	var confirm bool
+	for _, target := range targets {
+		assumeClusterAdmin := opts.AssumeClusterAdmin != nil && opts.AssumeClusterAdmin(target.Context)
+		targetArgs, targetConfirm := b.BuildKubectlArgs(target.Context, target.Namespace, target.AllNamespaces, assumeClusterAdmin, slices.Clone(args))
+		kubectlArgs[target] = targetArgs
+		confirm = confirm || targetConfirm
+	}
+
+	needsConfirm := confirm && !opts.Confirmed
+	if opts.Debug || needsConfirm {
+		for _, target := range targets {
+			debugPrintf(target.Context, "%s %s", Kubectl, strings.Join(kubectlArgs[target], " "))
+		}
+	}
+
+	if needsConfirm {
+		res := mdexec.GetConfirmation(fmt.Sprintf("Do you want to execute the above %d commands?", len(targets)))
+		if !res {
+			return errors.New("command canceled")
+		}
+	}
+
+	return RunFanout(targets, opts.Parallelism, os.Stdout, os.Stderr, func(target Target, stdout, stderr io.Writer) error {
+		if opts.Jq != nil {
+			return runKubectlJq(kubectlArgs[target], opts.Jq, nil, stdout, stderr)
+		}
+
+		c := exec.Command(Kubectl, kubectlArgs[target]...)
+		c.Stdout = stdout
+		c.Stderr = stderr
+		return c.Run()
+	})
+}
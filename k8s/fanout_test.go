@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchNames(t *testing.T) {
+	names := []string{"tidb-mussel-prod", "tidb-mussel-prod-dr", "tidb-mussel-stg", "kube-system"}
+
+	testCases := []struct {
+		name        string
+		patterns    []string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "Glob",
+			patterns: []string{"tidb-mussel-prod*"},
+			expected: []string{"tidb-mussel-prod", "tidb-mussel-prod-dr"},
+		},
+		{
+			name:     "Multiple patterns keep name order",
+			patterns: []string{"kube-*", "tidb-mussel-stg"},
+			expected: []string{"tidb-mussel-stg", "kube-system"},
+		},
+		{
+			name:     "No matches",
+			patterns: []string{"foo*"},
+			expected: []string{},
+		},
+		{
+			name:        "Invalid pattern",
+			patterns:    []string{"[tidb"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := matchNames(names, tc.patterns)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestFanoutTargets(t *testing.T) {
+	resolve := func(context, namespace string) (string, string) {
+		return "m-" + context, strings.TrimPrefix(namespace, "alias-")
+	}
+
+	targets, err := FanoutTargets([]string{"a", "b", "a"}, []string{"alias-ns1", "ns2"}, false, resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, []Target{
+		{Context: "m-a", Namespace: "ns1"},
+		{Context: "m-a", Namespace: "ns2"},
+		{Context: "m-b", Namespace: "ns1"},
+		{Context: "m-b", Namespace: "ns2"},
+	}, targets)
+
+	targets, err = FanoutTargets([]string{"a", "b"}, []string{"ns1"}, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Target{
+		{Context: "a", AllNamespaces: true},
+		{Context: "b", AllNamespaces: true},
+	}, targets)
+	assert.Equal(t, "a/*", targets[0].String())
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&out, &mu, "a | ")
+
+	_, err := io.WriteString(w, "first\nsec")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "ond\nthird")
+	assert.NoError(t, err)
+	assert.Equal(t, "a | first\na | second\n", out.String())
+
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "a | first\na | second\na | third\n", out.String())
+}
+
+func TestRunFanout(t *testing.T) {
+	targets := []Target{
+		{Context: "a", Namespace: "ns"},
+		{Context: "b", Namespace: "ns"},
+		{Context: "c", Namespace: "ns"},
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := RunFanout(targets, 2, &stdout, &stderr, func(target Target, stdout, stderr io.Writer) error {
+		_, _ = fmt.Fprintf(stdout, "hello from %s\n", target.Context)
+		if target.Context == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	var fanoutErr *FanoutError
+	assert.ErrorAs(t, err, &fanoutErr)
+	assert.Equal(t, []Target{targets[1]}, fanoutErr.Failed)
+	assert.Equal(t, 1, fanoutErr.ExitCode())
+
+	for _, target := range targets {
+		assert.Contains(t, stdout.String(), fmt.Sprintf("%s | hello from %s\n", target.String(), target.Context))
+	}
+	assert.Contains(t, stderr.String(), "b/ns | boom")
+
+	err = RunFanout(targets, 2, &stdout, &stderr, func(target Target, stdout, stderr io.Writer) error {
+		return nil
+	})
+	assert.NoError(t, err)
+}
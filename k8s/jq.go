@@ -0,0 +1,166 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"github.com/urfave/cli/v2"
+)
+
+var JqFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "jq",
+		Value: "",
+		Usage: "Filter the JSON output with a jq `EXPR`. Forces -o json",
+	},
+	&cli.BoolFlag{
+		Name:    "raw",
+		Aliases: []string{"r"},
+		Value:   false,
+		Usage:   "Output strings from --jq without quotes",
+	},
+}
+
+// JqFilter is a compiled jq expression applied to kubectl JSON output.
+type JqFilter struct {
+	code *gojq.Code
+	// Output strings without quotes
+	Raw bool
+}
+
+// NewJqFilter compiles expr.
+func NewJqFilter(expr string, raw bool) (*JqFilter, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression: %w", err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression: %w", err)
+	}
+	return &JqFilter{code: code, Raw: raw}, nil
+}
+
+// ParseJqFlags returns the filter from JqFlags, or nil if --jq isn't set.
+func ParseJqFlags(cCtx *cli.Context) (*JqFilter, error) {
+	expr := cCtx.String("jq")
+	if expr == "" {
+		return nil, nil
+	}
+	return NewJqFilter(expr, cCtx.Bool("raw"))
+}
+
+// Apply runs the filter over each JSON document in data, writing each result
+// to w.
+func (f *JqFilter) Apply(data []byte, w io.Writer) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		var input any
+		if err := decoder.Decode(&input); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to parse JSON output: %w", err)
+		}
+
+		iter := f.code.Run(input)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, ok := v.(error); ok {
+				return err
+			}
+
+			if s, ok := v.(string); ok && f.Raw {
+				if _, err := fmt.Fprintln(w, s); err != nil {
+					return err
+				}
+				continue
+			}
+
+			out, err := gojq.Marshal(v)
+			if err != nil {
+				return err
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, out, "", "  "); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, indented.String()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WithJSONOutput replaces any output format in the kubectl args with -o json.
+// Args after -- are left untouched.
+func WithJSONOutput(args []string) []string {
+	result := make([]string, 0, len(args)+2)
+	end := len(args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			end = i
+			break
+		}
+
+		switch {
+		case arg == "-o" || arg == "--output":
+			// Skip the format value too
+			i++
+		case strings.HasPrefix(arg, "-o") || strings.HasPrefix(arg, "--output="):
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	result = append(result, "-o", "json")
+	return append(result, args[end:]...)
+}
+
+// runKubectlJq runs kubectl with args and writes its output filtered by jq to
+// stdout.
+func runKubectlJq(args []string, jq *JqFilter, stdin io.Reader, stdout, stderr io.Writer) error {
+	var output bytes.Buffer
+	c := exec.Command(Kubectl, args...)
+	c.Stdin = stdin
+	c.Stdout = &output
+	c.Stderr = stderr
+	if err := c.Run(); err != nil {
+		return err
+	}
+
+	return jq.Apply(output.Bytes(), stdout)
+}
+
+// RunKubectlJq runs kubectl with args and prints its output filtered by jq.
+func RunKubectlJq(args []string, jq *JqFilter) error {
+	return runKubectlJq(args, jq, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// DecodeKubectl runs kubectl with the built kubectlArgs, which should request
+// -o json, and decodes the output into v.
+func DecodeKubectl(kubectlArgs []string, v any) error {
+	c := exec.Command(Kubectl, kubectlArgs...)
+	c.Stderr = os.Stderr
+	output, err := c.Output()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", Kubectl, strings.Join(kubectlArgs, " "), err)
+	}
+
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse output of %s %s: %w", Kubectl, strings.Join(kubectlArgs, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithJSONOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "No output",
+			args:     []string{"get", "pods"},
+			expected: []string{"get", "pods", "-o", "json"},
+		},
+		{
+			name:     "Separate output",
+			args:     []string{"get", "pods", "-o", "wide", "-l", "app=foo"},
+			expected: []string{"get", "pods", "-l", "app=foo", "-o", "json"},
+		},
+		{
+			name:     "Joined outputs",
+			args:     []string{"get", "-oyaml", "pods", "--output=name"},
+			expected: []string{"get", "pods", "-o", "json"},
+		},
+		{
+			name:     "Long output",
+			args:     []string{"get", "--output", "jsonpath={.items}", "pods"},
+			expected: []string{"get", "pods", "-o", "json"},
+		},
+		{
+			name:     "Args after separator",
+			args:     []string{"exec", "foo", "--", "ls", "-o", "x"},
+			expected: []string{"exec", "foo", "-o", "json", "--", "ls", "-o", "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, WithJSONOutput(tt.args))
+		})
+	}
+}
+
+func TestJqFilterApply(t *testing.T) {
+	input := []byte(`{"items":[{"metadata":{"name":"a"},"id":18446744073709551615},{"metadata":{"name":"b"},"id":2}]}`)
+
+	tests := []struct {
+		name     string
+		expr     string
+		raw      bool
+		expected string
+	}{
+		{
+			name:     "Strings",
+			expr:     ".items[].metadata.name",
+			expected: "\"a\"\n\"b\"\n",
+		},
+		{
+			name:     "Raw strings",
+			expr:     ".items[].metadata.name",
+			raw:      true,
+			expected: "a\nb\n",
+		},
+		{
+			name:     "Large numbers",
+			expr:     ".items[0].id",
+			expected: "18446744073709551615\n",
+		},
+		{
+			name:     "Objects",
+			expr:     ".items[1] | {name: .metadata.name}",
+			raw:      true,
+			expected: "{\n  \"name\": \"b\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jq, err := NewJqFilter(tt.expr, tt.raw)
+			assert.NoError(t, err)
+
+			var out bytes.Buffer
+			assert.NoError(t, jq.Apply(input, &out))
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+
+	_, err := NewJqFilter(".items[", false)
+	assert.Error(t, err)
+
+	jq, err := NewJqFilter(".items | error", false)
+	assert.NoError(t, err)
+	assert.Error(t, jq.Apply(input, &bytes.Buffer{}))
+}
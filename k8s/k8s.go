@@ -2,12 +2,8 @@ package k8s
 
 import (
 	"errors"
-	"fmt"
-	"os"
-	"os/exec"
 	"strings"
-
-	"github.com/michaelmdeng/mdcli/internal/cmd"
+	"time"
 )
 
 var (
@@ -67,102 +63,114 @@ func isModifiableResource(resource string) bool {
 	return ok
 }
 
+// GetContextInteractive picks a context matching pattern with fzf, ordering
+// recently used contexts first.
 func GetContextInteractive(pattern string) (string, error) {
-	var contextCmd string
-	if len(pattern) > 0 {
-		contextCmd = fmt.Sprintf("yq eval '.contexts[].name' /Users/michael_deng/.kube/config | grep -e \"%s\"", pattern)
-	} else {
-		contextCmd = "yq eval '.contexts[].name' /Users/michael_deng/.kube/config"
-	}
-	c := exec.Command("fzf", "--ansi", "--no-preview", "--prompt", "Select kubecontext> ")
-	c.Stdin = os.Stdin
-	c.Stderr = os.Stderr
-	c.Env = append(os.Environ(),
-		fmt.Sprintf("FZF_DEFAULT_COMMAND=%s", contextCmd),
-	)
-	context, err := cmd.CaptureCmd(*c)
+	contexts, err := listContexts()
+	if err != nil {
+		return "", err
+	}
+	contexts, err = filterNames(contexts, pattern)
 	if err != nil {
 		return "", err
 	}
-	if context == "" {
-		return "", errors.New("no context selected")
+
+	usages, err := loadRecentUsages()
+	if err != nil {
+		return "", err
 	}
+	items := rankItems(contexts, contextScores(usages, time.Now()))
 
-	return strings.TrimSpace(context), nil
+	fields, err := pick("Select kubecontext> ", pickerLines(items))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(fields[0]), nil
 }
 
+// GetNamespaceInteractive picks a namespace in context matching pattern with
+// fzf, ordering namespaces recently used in the context first.
 func GetNamespaceInteractive(context string, pattern string) (string, error) {
-	var namespaceCmd string
-	if context == "" {
-		namespaceCmd = "kubectl get ns -o jsonpath='{range .items[*]}{.metadata.name}{\"\\n\"}{end}'"
-	} else {
-		namespaceCmd = fmt.Sprintf("kubectl --context %s get ns -o jsonpath='{range .items[*]}{.metadata.name}{\"\\n\"}{end}'", context)
-	}
-	if len(pattern) > 0 {
-		namespaceCmd = fmt.Sprintf("%s | grep %s", namespaceCmd, pattern)
-	}
-
-	c := exec.Command("fzf", "--ansi", "--no-preview", "--prompt", "Select namespace> ")
-	c.Stdin = os.Stdin
-	c.Stderr = os.Stderr
-	c.Env = append(os.Environ(),
-		fmt.Sprintf("FZF_DEFAULT_COMMAND=%s", namespaceCmd),
-	)
-	namespace, err := cmd.CaptureCmd(*c)
+	namespaces, err := listNamespaces(context)
 	if err != nil {
 		return "", err
 	}
-	if namespace == "" {
-		return "", errors.New("no namespace selected")
+	namespaces, err = filterNames(namespaces, pattern)
+	if err != nil {
+		return "", err
 	}
 
-	return strings.TrimSpace(namespace), nil
+	usages, err := loadRecentUsages()
+	if err != nil {
+		return "", err
+	}
+	items := rankItems(namespaces, namespaceScores(usages, context, time.Now()))
+
+	fields, err := pick("Select namespace> ", pickerLines(items))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(fields[0]), nil
 }
 
-func ParseContext(context string, interactive bool, pattern string, strict bool) (string, error) {
+// ParseContext resolves the context from the flag, the sticky state or a
+// picker. The note describes any sticky state used.
+func ParseContext(context string, interactive bool, pattern string, strict bool) (string, string, error) {
 	if context != "" {
-		return context, nil
+		return context, "", nil
+	}
+
+	if sticky, note, ok := StickyContext(pattern, nil); ok {
+		return sticky, note, nil
 	}
 
 	if interactive && context == "" {
 		var err error
 		context, err = GetContextInteractive(pattern)
 		if strict && err != nil {
-			return "", err
+			return "", "", err
 		} else if err != nil {
 			context = ""
 		}
 	}
 
 	if strict && context == "" {
-		return "", errors.New("context must be specified in strict mode")
+		return "", "", errors.New("context must be specified in strict mode")
 	}
 
-	return context, nil
+	return context, "", nil
 }
 
-func ParseNamespace(namespace string, allNamespaces bool, interactive bool, context string, pattern string, strict bool) (string, bool, error) {
+// ParseNamespace resolves the namespace like ParseContext, returning whether
+// all namespaces were selected. Namespaces chosen with the picker are recorded
+// as recent.
+func ParseNamespace(namespace string, allNamespaces bool, interactive bool, context string, pattern string, strict bool) (string, bool, string, error) {
 	if allNamespaces || namespace == "*" {
-		return "", true, nil
+		return "", true, "", nil
 	}
 
 	if namespace != "" {
-		return namespace, false, nil
+		return namespace, false, "", nil
+	}
+
+	if sticky, note, ok := StickyNamespace(context, pattern, nil); ok {
+		return sticky, false, note, nil
 	}
 
 	if interactive && !allNamespaces && namespace == "" {
 		var err error
 		namespace, err = GetNamespaceInteractive(context, pattern)
 		if strict && err != nil {
-			return "", false, err
+			return "", false, "", err
 		} else if err != nil {
 			namespace = ""
 		}
+		RecordRecentTarget(context, namespace)
 	}
 
 	if strict && namespace == "" {
-		return "", false, errors.New("namespace must be specified in strict mode")
+		return "", false, "", errors.New("namespace must be specified in strict mode")
 	}
 
-	return namespace, false, nil
+	return namespace, false, "", nil
 }
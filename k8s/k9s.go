@@ -0,0 +1,279 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvProd    = "prod"
+	EnvStg     = "stg"
+	EnvTest    = "test"
+	EnvDefault = "default"
+)
+
+// k9sEnvColors are the accent colours of the generated k9s skins, matching the
+// colours of tidb debug output.
+var k9sEnvColors = map[string]string{
+	EnvProd: "red",
+	EnvStg:  "yellow",
+	EnvTest: "magenta",
+}
+
+// k9sSharedFiles are linked from the user's k9s config into each generated
+// config dir so they keep working under the wrapper.
+var k9sSharedFiles = []string{"aliases.yaml", "hotkeys.yaml", "views.yaml"}
+
+// ContextEnvironment classifies context as prod, stg or test by name, or
+// default if it matches none.
+func ContextEnvironment(context string) string {
+	switch {
+	case strings.Contains(context, "prod"):
+		return EnvProd
+	case strings.Contains(context, "stg"):
+		return EnvStg
+	case strings.Contains(context, "test"):
+		return EnvTest
+	default:
+		return EnvDefault
+	}
+}
+
+// UserK9sConfigDir is the k9s config dir used without the wrapper.
+func UserK9sConfigDir() (string, error) {
+	if dir := os.Getenv("K9S_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "k9s"), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "k9s"), nil
+}
+
+func k9sSkinName(env string) string {
+	return "mdcli-" + env
+}
+
+// k9sSkin returns a skin which highlights the frame, logo and crumbs in color.
+func k9sSkin(color string) map[string]any {
+	return map[string]any{
+		"k9s": map[string]any{
+			"body": map[string]any{
+				"logoColor": color,
+			},
+			"frame": map[string]any{
+				"border": map[string]any{
+					"fgColor":    color,
+					"focusColor": color,
+				},
+				"title": map[string]any{
+					"fgColor":        color,
+					"highlightColor": color,
+				},
+				"crumbs": map[string]any{
+					"fgColor":     "black",
+					"bgColor":     color,
+					"activeColor": color,
+				},
+				"menu": map[string]any{
+					"keyColor": color,
+				},
+			},
+			"info": map[string]any{
+				"sectionColor": color,
+			},
+			"prompt": map[string]any{
+				"fgColor": color,
+			},
+		},
+	}
+}
+
+// k9sPlugins returns plugins calling back into mdcli at executable.
+func k9sPlugins(executable string) map[string]any {
+	quoted := shellQuote(executable)
+	target := `-c "$CONTEXT" -n "$NAMESPACE" --interactive=false`
+	return map[string]any{
+		"mdcli-tidb-mysql": map[string]any{
+			"shortCut":    "Shift-Q",
+			"description": "TiDB mysql",
+			"scopes":      []string{"tidbclusters", "pods"},
+			"command":     "sh",
+			"background":  false,
+			"args":        []string{"-c", fmt.Sprintf("%s tidb mysql %s", quoted, target)},
+		},
+		"mdcli-tidb-pdctl": map[string]any{
+			"shortCut":    "Shift-W",
+			"description": "TiDB pdctl",
+			"scopes":      []string{"tidbclusters", "pods"},
+			"command":     "sh",
+			"background":  false,
+			"args":        []string{"-c", fmt.Sprintf("%s tidb pdctl %s", quoted, target)},
+		},
+		"mdcli-tikv-get": map[string]any{
+			"shortCut":    "Shift-G",
+			"description": "TiKV info",
+			"scopes":      []string{"pods"},
+			"command":     "sh",
+			"background":  false,
+			"args":        []string{"-c", fmt.Sprintf(`%s tidb tikv get %s "$NAME" | less`, quoted, target)},
+		},
+	}
+}
+
+func readYAML(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	v := map[string]any{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if v == nil {
+		v = map[string]any{}
+	}
+	return v, nil
+}
+
+func writeYAML(path string, v any) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// nestedMap returns the map at key in m, creating it if missing.
+func nestedMap(m map[string]any, key string) map[string]any {
+	if nested, ok := m[key].(map[string]any); ok {
+		return nested
+	}
+	nested := map[string]any{}
+	m[key] = nested
+	return nested
+}
+
+// generatedK9sDir is the generated k9s config dir for env.
+func generatedK9sDir(env string) (string, error) {
+	return state.Path("k9s", env)
+}
+
+// GenerateK9sConfig writes a k9s config dir per environment, each based on the
+// user's k9s config with an environment skin and mdcli plugins added. It
+// returns the generated dirs.
+func GenerateK9sConfig(executable string) ([]string, error) {
+	userDir, err := UserK9sConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	if base, err := state.Path("k9s"); err == nil && strings.HasPrefix(userDir, base) {
+		return nil, fmt.Errorf("K9S_CONFIG_DIR is already a generated config dir %s", userDir)
+	}
+
+	userPlugins, err := readYAML(filepath.Join(userDir, "plugins.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0)
+	for _, env := range []string{EnvProd, EnvStg, EnvTest, EnvDefault} {
+		dir, err := generatedK9sDir(env)
+		if err != nil {
+			return nil, err
+		}
+
+		// Read the user config per env since it is modified in place
+		config, err := readYAML(filepath.Join(userDir, "config.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		if color, ok := k9sEnvColors[env]; ok {
+			if err := writeYAML(filepath.Join(dir, "skins", k9sSkinName(env)+".yaml"), k9sSkin(color)); err != nil {
+				return nil, err
+			}
+			nestedMap(nestedMap(config, "k9s"), "ui")["skin"] = k9sSkinName(env)
+		}
+		if err := writeYAML(filepath.Join(dir, "config.yaml"), config); err != nil {
+			return nil, err
+		}
+
+		plugins := map[string]any{}
+		for name, plugin := range nestedMap(userPlugins, "plugins") {
+			plugins[name] = plugin
+		}
+		for name, plugin := range k9sPlugins(executable) {
+			plugins[name] = plugin
+		}
+		if err := writeYAML(filepath.Join(dir, "plugins.yaml"), map[string]any{"plugins": plugins}); err != nil {
+			return nil, err
+		}
+
+		shared := slices.Clone(k9sSharedFiles)
+		if skins, err := os.ReadDir(filepath.Join(userDir, "skins")); err == nil {
+			for _, skin := range skins {
+				shared = append(shared, filepath.Join("skins", skin.Name()))
+			}
+		}
+		for _, name := range shared {
+			if err := linkK9sFile(filepath.Join(userDir, name), filepath.Join(dir, name)); err != nil {
+				return nil, err
+			}
+		}
+
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// linkK9sFile symlinks dst to the user's k9s file at src, if it exists.
+func linkK9sFile(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(src, dst)
+}
+
+// K9sConfigDir returns the generated k9s config dir for the environment of
+// context, if `mdcli k8s k9s-config generate` has been run.
+func K9sConfigDir(context string) (string, bool) {
+	dir, err := generatedK9sDir(ContextEnvironment(context))
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(filepath.Join(dir, "plugins.yaml")); err != nil {
+		return "", false
+	}
+	return dir, true
+}
@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextEnvironment(t *testing.T) {
+	assert.Equal(t, EnvProd, ContextEnvironment("m-tidb-prod-a-ea1-us"))
+	assert.Equal(t, EnvStg, ContextEnvironment("m-tidb-stg-a-ea1-us"))
+	assert.Equal(t, EnvTest, ContextEnvironment("m-tidb-test-a-ea1-us"))
+	assert.Equal(t, EnvDefault, ContextEnvironment("minikube"))
+}
+
+func TestGenerateK9sConfig(t *testing.T) {
+	stateDir := t.TempDir()
+	userDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+	t.Setenv("K9S_CONFIG_DIR", userDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(userDir, "config.yaml"), []byte("k9s:\n  refreshRate: 2\n  ui:\n    skin: mine\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(userDir, "plugins.yaml"), []byte("plugins:\n  mine:\n    shortCut: Ctrl-L\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(userDir, "aliases.yaml"), []byte("aliases: {}\n"), 0644))
+
+	dirs, err := GenerateK9sConfig("/usr/local/bin/mdcli")
+	assert.NoError(t, err)
+	assert.Len(t, dirs, 4)
+
+	dir, ok := K9sConfigDir("m-tidb-prod-a-ea1-us")
+	assert.True(t, ok)
+
+	config, err := readYAML(filepath.Join(dir, "config.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"k9s": map[string]any{
+			"refreshRate": 2,
+			"ui":          map[string]any{"skin": "mdcli-prod"},
+		},
+	}, config)
+
+	skin, err := readYAML(filepath.Join(dir, "skins", "mdcli-prod.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "red", skin["k9s"].(map[string]any)["body"].(map[string]any)["logoColor"])
+
+	plugins, err := readYAML(filepath.Join(dir, "plugins.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, plugins["plugins"], "mine")
+	assert.Contains(t, plugins["plugins"], "mdcli-tidb-mysql")
+
+	link, err := os.Readlink(filepath.Join(dir, "aliases.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(userDir, "aliases.yaml"), link)
+
+	// The default environment keeps the user's skin
+	dir, ok = K9sConfigDir("minikube")
+	assert.True(t, ok)
+	config, err = readYAML(filepath.Join(dir, "config.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "mine", config["k9s"].(map[string]any)["ui"].(map[string]any)["skin"])
+
+	// Regenerating replaces the links
+	_, err = GenerateK9sConfig("/usr/local/bin/mdcli")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Kubeconfig is a kubeconfig file, loaded with client-go so fields mdcli
+// doesn't use, ie. extensions and exec plugin settings, survive a rewrite.
+type Kubeconfig struct {
+	*clientcmdapi.Config
+}
+
+// MergeResult lists the entries added to and skipped by Merge.
+type MergeResult struct {
+	Added   []string
+	Skipped []string
+}
+
+// KubeconfigPath returns path if set, otherwise the first file in KUBECONFIG
+// or ~/.kube/config.
+func KubeconfigPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0], nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// LoadKubeconfig parses the kubeconfig at path.
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &Kubeconfig{Config: config}, nil
+}
+
+// Save copies the existing file at path to path.bak, then atomically writes
+// the kubeconfig to path.
+func (k *Kubeconfig) Save(path string) error {
+	data, err := clientcmd.Write(*k.Config)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0600)
+	if existing, err := os.ReadFile(path); err == nil {
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if err := state.WriteFileAtomic(path+".bak", existing, perm); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return state.WriteFileAtomic(path, data, perm)
+}
+
+// RenameContext renames the context, following it in current-context.
+func (k *Kubeconfig) RenameContext(from, to string) error {
+	context, ok := k.Contexts[from]
+	if !ok {
+		return fmt.Errorf("context %s not found", from)
+	}
+	if _, ok := k.Contexts[to]; ok {
+		return fmt.Errorf("context %s already exists", to)
+	}
+
+	delete(k.Contexts, from)
+	k.Contexts[to] = context
+	if k.CurrentContext == from {
+		k.CurrentContext = to
+	}
+	return nil
+}
+
+// RemoveContext removes the context, and its cluster and user if no other
+// context references them. It returns the removed entries.
+func (k *Kubeconfig) RemoveContext(name string) ([]string, error) {
+	context, ok := k.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", name)
+	}
+
+	delete(k.Contexts, name)
+	if k.CurrentContext == name {
+		k.CurrentContext = ""
+	}
+	return append([]string{"context/" + name}, k.removeOrphans(context.Cluster, context.AuthInfo)...), nil
+}
+
+// removeOrphans removes the cluster and user if no context references them.
+func (k *Kubeconfig) removeOrphans(cluster, user string) []string {
+	clusterUsed, userUsed := false, false
+	for _, c := range k.Contexts {
+		clusterUsed = clusterUsed || c.Cluster == cluster
+		userUsed = userUsed || c.AuthInfo == user
+	}
+
+	removed := make([]string, 0)
+	if _, ok := k.Clusters[cluster]; ok && !clusterUsed {
+		delete(k.Clusters, cluster)
+		removed = append(removed, "cluster/"+cluster)
+	}
+	if _, ok := k.AuthInfos[user]; ok && !userUsed {
+		delete(k.AuthInfos, user)
+		removed = append(removed, "user/"+user)
+	}
+	return removed
+}
+
+// DanglingContexts returns the contexts whose cluster entry is missing.
+func (k *Kubeconfig) DanglingContexts() []string {
+	dangling := make([]string, 0)
+	for _, name := range slices.Sorted(maps.Keys(k.Contexts)) {
+		if _, ok := k.Clusters[k.Contexts[name].Cluster]; !ok {
+			dangling = append(dangling, name)
+		}
+	}
+	return dangling
+}
+
+// Merge adds the clusters, users and contexts of other. Entries whose name
+// already exists are skipped unless overwrite is set.
+func (k *Kubeconfig) Merge(other *Kubeconfig, overwrite bool) MergeResult {
+	var result MergeResult
+	track := func(kind, name string, exists bool) bool {
+		entry := kind + "/" + name
+		if exists && !overwrite {
+			result.Skipped = append(result.Skipped, entry)
+			return false
+		}
+		result.Added = append(result.Added, entry)
+		return true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(other.Clusters)) {
+		_, exists := k.Clusters[name]
+		if track("cluster", name, exists) {
+			k.Clusters[name] = other.Clusters[name]
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(other.AuthInfos)) {
+		_, exists := k.AuthInfos[name]
+		if track("user", name, exists) {
+			k.AuthInfos[name] = other.AuthInfos[name]
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(other.Contexts)) {
+		_, exists := k.Contexts[name]
+		if track("context", name, exists) {
+			k.Contexts[name] = other.Contexts[name]
+		}
+	}
+	return result
+}
+
+// ContextStatus is a context listed by `mdcli k8s contexts ls`.
+type ContextStatus struct {
+	Name      string
+	Cluster   string
+	Server    string
+	Current   bool
+	Reachable bool
+}
+
+// ContextStatuses lists the contexts matching pattern. If check is set, the
+// cluster servers are dialed concurrently to see if they are reachable.
+func (k *Kubeconfig) ContextStatuses(pattern string, check bool, timeout time.Duration) ([]ContextStatus, error) {
+	names, err := filterNames(slices.Sorted(maps.Keys(k.Contexts)), pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ContextStatus, 0, len(names))
+	for _, name := range names {
+		c := k.Contexts[name]
+		var server string
+		if cluster, ok := k.Clusters[c.Cluster]; ok {
+			server = cluster.Server
+		}
+		statuses = append(statuses, ContextStatus{
+			Name:    name,
+			Cluster: c.Cluster,
+			Server:  server,
+			Current: name == k.CurrentContext,
+		})
+	}
+
+	if check {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 16)
+		for i := range statuses {
+			wg.Add(1)
+			go func(status *ContextStatus) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				status.Reachable = serverReachable(status.Server, timeout)
+			}(&statuses[i])
+		}
+		wg.Wait()
+	}
+	return statuses, nil
+}
+
+// serverReachable returns whether a TCP connection to the API server can be
+// opened. It doesn't authenticate.
+func serverReachable(server string, timeout time.Duration) bool {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		port := "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+		host = net.JoinHostPort(strings.Trim(u.Hostname(), "[]"), port)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -0,0 +1,227 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const kubeconfigFixture = `apiVersion: v1
+kind: Config
+preferences: {}
+clusters:
+  - name: prod
+    cluster:
+      server: https://prod.example.com
+      certificate-authority-data: Y2E=
+  - name: stg
+    cluster:
+      server: https://stg.example.com
+contexts:
+  - name: m-tidb-prod-a
+    context:
+      cluster: prod
+      user: admin
+      namespace: tidb-merge
+  - name: m-tidb-prod-b
+    context:
+      cluster: prod
+      user: admin
+  - name: m-tidb-stg-a
+    context:
+      cluster: stg
+      user: stg-admin
+  - name: m-tidb-old
+    context:
+      cluster: gone
+      user: old-admin
+users:
+  - name: admin
+    user:
+      token: abc
+  - name: stg-admin
+    user:
+      exec:
+        command: aws
+  - name: old-admin
+    user:
+      token: old
+current-context: m-tidb-prod-a
+`
+
+func loadFixture(t *testing.T) (*Kubeconfig, string) {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(path, []byte(kubeconfigFixture), 0600))
+
+	k, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	return k, path
+}
+
+func TestKubeconfigRoundTrip(t *testing.T) {
+	k, path := loadFixture(t)
+	assert.NoError(t, k.Save(path))
+
+	backup, err := os.ReadFile(path + ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, kubeconfigFixture, string(backup))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	saved, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, k, saved)
+	assert.Equal(t, []byte("ca"), saved.Clusters["prod"].CertificateAuthorityData)
+}
+
+// kubectlFixture is written by kubectl and has fields mdcli doesn't use.
+const kubectlFixture = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2E=
+    server: https://prod.example.com
+  name: prod
+- cluster:
+    extensions:
+    - extension:
+        last-update: Mon, 01 Jul 2025 12:00:00 UTC
+        provider: minikube.sigs.k8s.io
+      name: cluster_info
+    server: https://stg.example.com
+  name: stg
+contexts:
+- context:
+    cluster: prod
+    namespace: tidb-merge
+    user: admin
+  name: m-tidb-prod-a
+- context:
+    cluster: stg
+    user: stg-admin
+  name: m-tidb-stg-a
+current-context: m-tidb-prod-a
+kind: Config
+preferences: {}
+users:
+- name: admin
+  user:
+    token: abc
+- name: stg-admin
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - eks
+      - get-token
+      - --cluster-name
+      - stg
+      command: aws
+      env:
+      - name: AWS_PROFILE
+        value: stg
+      interactiveMode: IfAvailable
+      provideClusterInfo: false
+`
+
+func TestKubeconfigRoundTripKubectl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(path, []byte(kubectlFixture), 0600))
+
+	// An untouched kubeconfig is written back as kubectl writes it
+	k, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, k.Save(path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, kubectlFixture, string(data))
+
+	// Removals keep the remaining entries' extensions and exec settings
+	_, err = k.RemoveContext("m-tidb-prod-a")
+	assert.NoError(t, err)
+	assert.NoError(t, k.Save(path))
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "provider: minikube.sigs.k8s.io")
+	assert.NotContains(t, string(data), "prod")
+
+	saved, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.Contains(t, saved.Clusters["stg"].Extensions, "cluster_info")
+	exec := saved.AuthInfos["stg-admin"].Exec
+	assert.Equal(t, []string{"eks", "get-token", "--cluster-name", "stg"}, exec.Args)
+	assert.Equal(t, "stg", exec.Env[0].Value)
+	assert.Equal(t, "IfAvailable", string(exec.InteractiveMode))
+}
+
+func TestRenameContext(t *testing.T) {
+	k, _ := loadFixture(t)
+
+	assert.NoError(t, k.RenameContext("m-tidb-prod-a", "prod-a"))
+	assert.Contains(t, k.Contexts, "prod-a")
+	assert.NotContains(t, k.Contexts, "m-tidb-prod-a")
+	assert.Equal(t, "tidb-merge", k.Contexts["prod-a"].Namespace)
+	assert.Equal(t, "prod-a", k.CurrentContext)
+
+	assert.Error(t, k.RenameContext("missing", "x"))
+	assert.Error(t, k.RenameContext("prod-a", "m-tidb-prod-b"))
+}
+
+func TestRemoveContext(t *testing.T) {
+	k, _ := loadFixture(t)
+
+	// The cluster and user are still used by m-tidb-prod-b
+	removed, err := k.RemoveContext("m-tidb-prod-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"context/m-tidb-prod-a"}, removed)
+	assert.Equal(t, "", k.CurrentContext)
+	assert.Len(t, k.Clusters, 2)
+
+	removed, err = k.RemoveContext("m-tidb-prod-b")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"context/m-tidb-prod-b", "cluster/prod", "user/admin"}, removed)
+	assert.Len(t, k.Clusters, 1)
+	assert.Len(t, k.AuthInfos, 2)
+
+	_, err = k.RemoveContext("missing")
+	assert.Error(t, err)
+}
+
+func TestDanglingContexts(t *testing.T) {
+	k, _ := loadFixture(t)
+	assert.Equal(t, []string{"m-tidb-old"}, k.DanglingContexts())
+}
+
+func TestMerge(t *testing.T) {
+	other := &Kubeconfig{Config: clientcmdapi.NewConfig()}
+	other.Clusters["stg"] = &clientcmdapi.Cluster{Server: "https://new-stg.example.com"}
+	other.Clusters["test"] = &clientcmdapi.Cluster{Server: "https://test.example.com"}
+	other.Contexts["m-tidb-test-a"] = &clientcmdapi.Context{Cluster: "test", AuthInfo: "test-admin"}
+	other.AuthInfos["test-admin"] = &clientcmdapi.AuthInfo{Token: "t"}
+
+	k, _ := loadFixture(t)
+	result := k.Merge(other, false)
+	assert.Equal(t, []string{"cluster/test", "user/test-admin", "context/m-tidb-test-a"}, result.Added)
+	assert.Equal(t, []string{"cluster/stg"}, result.Skipped)
+	assert.Equal(t, "https://stg.example.com", k.Clusters["stg"].Server)
+
+	k, _ = loadFixture(t)
+	result = k.Merge(other, true)
+	assert.Empty(t, result.Skipped)
+	assert.Equal(t, "https://new-stg.example.com", k.Clusters["stg"].Server)
+}
+
+func TestContextStatuses(t *testing.T) {
+	k, _ := loadFixture(t)
+
+	statuses, err := k.ContextStatuses("prod", false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []ContextStatus{
+		{Name: "m-tidb-prod-a", Cluster: "prod", Server: "https://prod.example.com", Current: true},
+		{Name: "m-tidb-prod-b", Cluster: "prod", Server: "https://prod.example.com"},
+	}, statuses)
+}
@@ -0,0 +1,434 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+)
+
+var LogsFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "selector",
+		Aliases: []string{"l"},
+		Usage:   "Label `SELECTOR` of pods to tail",
+	},
+	&cli.StringFlag{
+		Name:    "workload",
+		Aliases: []string{"w"},
+		Usage:   "Tail pods of `WORKLOAD`, ie. deploy/foo or sts/foo",
+	},
+	&cli.StringFlag{
+		Name:  "container",
+		Usage: "Only tail containers matching `REGEX`",
+	},
+	&cli.StringFlag{
+		Name:  "since",
+		Usage: "Only return logs newer than a relative `DURATION`, ie. 5m",
+	},
+	&cli.StringFlag{
+		Name:    "grep",
+		Aliases: []string{"g"},
+		Usage:   "Only show lines matching `REGEX`",
+	},
+	&cli.StringFlag{
+		Name:    "exclude",
+		Aliases: []string{"x"},
+		Usage:   "Hide lines matching `REGEX`",
+	},
+	&cli.BoolFlag{
+		Name:    "follow",
+		Aliases: []string{"f"},
+		Value:   true,
+		Usage:   "Follow logs and pick up new pods as they appear",
+	},
+	&cli.DurationFlag{
+		Name:  "poll-interval",
+		Value: 5 * time.Second,
+		Usage: "How often to check for new pods when following",
+	},
+}
+
+// LogsOptions configures TailLogs.
+type LogsOptions struct {
+	Context   string
+	Namespace string
+	// Label selector of pods to tail
+	Selector string
+	// Workload whose selector is used to find pods, ie. deploy/foo
+	Workload string
+	// Only tail pods whose name matches
+	PodPattern *regexp.Regexp
+	// Only tail containers whose name matches
+	ContainerPattern *regexp.Regexp
+	Since            string
+	// Only show lines matching Grep and not matching Exclude
+	Grep               *regexp.Regexp
+	Exclude            *regexp.Regexp
+	Follow             bool
+	PollInterval       time.Duration
+	AssumeClusterAdmin bool
+	Debug              bool
+	DebugPrintf        func(context string, format string, args ...any)
+}
+
+func compileOptionalRegex(flag string, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+	}
+	return re, nil
+}
+
+// NewLogsOptions parses LogsFlags and an optional pod name regex argument.
+// Context and namespace are left for the caller to resolve.
+func NewLogsOptions(cCtx *cli.Context) (LogsOptions, error) {
+	opts := LogsOptions{
+		Selector:           cCtx.String("selector"),
+		Workload:           cCtx.String("workload"),
+		Since:              cCtx.String("since"),
+		Follow:             cCtx.Bool("follow"),
+		PollInterval:       cCtx.Duration("poll-interval"),
+		AssumeClusterAdmin: cCtx.Bool("assume-cluster-admin"),
+		Debug:              cCtx.Bool("debug"),
+	}
+
+	if cCtx.NArg() > 1 {
+		return LogsOptions{}, errors.New("at most one pod name regex can be provided")
+	}
+
+	var err error
+	if opts.PodPattern, err = compileOptionalRegex("pod", cCtx.Args().First()); err != nil {
+		return LogsOptions{}, err
+	}
+	if opts.ContainerPattern, err = compileOptionalRegex("container", cCtx.String("container")); err != nil {
+		return LogsOptions{}, err
+	}
+	if opts.Grep, err = compileOptionalRegex("grep", cCtx.String("grep")); err != nil {
+		return LogsOptions{}, err
+	}
+	if opts.Exclude, err = compileOptionalRegex("exclude", cCtx.String("exclude")); err != nil {
+		return LogsOptions{}, err
+	}
+
+	if opts.Selector == "" && opts.Workload == "" && opts.PodPattern == nil {
+		return LogsOptions{}, errors.New("one of a pod name regex, --selector or --workload must be provided")
+	}
+
+	return opts, nil
+}
+
+type logStream struct {
+	Pod       string
+	Container string
+}
+
+func (s logStream) String() string {
+	return fmt.Sprintf("%s/%s", s.Pod, s.Container)
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec   podSpec `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type workloadSelector struct {
+	Spec struct {
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+}
+
+func (b *KubeBuilder) captureKubectl(context, namespace string, assumeClusterAdmin bool, args []string) ([]byte, error) {
+	kubectlArgs, _ := b.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, args)
+	c := exec.Command(Kubectl, kubectlArgs...)
+	c.Stderr = os.Stderr
+	return c.Output()
+}
+
+func (b *KubeBuilder) workloadSelector(opts LogsOptions) (string, error) {
+	output, err := b.captureKubectl(opts.Context, opts.Namespace, false, []string{"get", opts.Workload, "-o", "json"})
+	if err != nil {
+		return "", fmt.Errorf("failed to get workload %s: %w", opts.Workload, err)
+	}
+
+	var workload workloadSelector
+	if err := json.Unmarshal(output, &workload); err != nil {
+		return "", err
+	}
+
+	labels := workload.Spec.Selector.MatchLabels
+	if len(labels) == 0 {
+		return "", fmt.Errorf("workload %s has no matchLabels selector", opts.Workload)
+	}
+
+	selectors := make([]string, 0, len(labels))
+	for k, v := range labels {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(selectors)
+	return strings.Join(selectors, ","), nil
+}
+
+// listLogStreams returns the containers of pods matching opts. Pods that have
+// completed are only included if includeCompleted is set.
+func (b *KubeBuilder) listLogStreams(opts LogsOptions, selector string, includeCompleted bool) ([]logStream, error) {
+	args := []string{"get", "pods", "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+
+	output, err := b.captureKubectl(opts.Context, opts.Namespace, false, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	var pods podList
+	if err := json.Unmarshal(output, &pods); err != nil {
+		return nil, err
+	}
+	return podLogStreams(opts, pods, includeCompleted), nil
+}
+
+// podLogStreams returns the containers of pods matching opts, skipping pending
+// pods and completed pods unless includeCompleted is set.
+func podLogStreams(opts LogsOptions, pods podList, includeCompleted bool) []logStream {
+	streams := make([]logStream, 0)
+	for _, pod := range pods.Items {
+		switch pod.Status.Phase {
+		case "Pending":
+			continue
+		case "Succeeded", "Failed":
+			if !includeCompleted {
+				continue
+			}
+		}
+		if opts.PodPattern != nil && !opts.PodPattern.MatchString(pod.Metadata.Name) {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			if opts.ContainerPattern != nil && !opts.ContainerPattern.MatchString(container.Name) {
+				continue
+			}
+			streams = append(streams, logStream{Pod: pod.Metadata.Name, Container: container.Name})
+		}
+	}
+	return streams
+}
+
+func (opts LogsOptions) filterLine(line []byte) bool {
+	if opts.Grep != nil && !opts.Grep.Match(line) {
+		return false
+	}
+	if opts.Exclude != nil && opts.Exclude.Match(line) {
+		return false
+	}
+	return true
+}
+
+// logStreams tracks the streams being tailed, so polling only starts streams
+// which aren't running and resumes ended ones where they stopped.
+type logStreams struct {
+	mu      sync.Mutex
+	active  map[logStream]bool
+	endedAt map[logStream]time.Time
+	colors  map[logStream]int
+}
+
+// streamStart is a stream to start tailing.
+type streamStart struct {
+	stream logStream
+	color  int
+	// When the stream last ended, or zero if it is new
+	since time.Time
+}
+
+func newLogStreams() *logStreams {
+	return &logStreams{
+		active:  make(map[logStream]bool),
+		endedAt: make(map[logStream]time.Time),
+		colors:  make(map[logStream]int),
+	}
+}
+
+// start returns the streams which aren't already running and marks them
+// active. A stream keeps its colour when it is restarted.
+func (s *logStreams) start(streams []logStream) []streamStart {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	starts := make([]streamStart, 0)
+	for _, stream := range streams {
+		if s.active[stream] {
+			continue
+		}
+		s.active[stream] = true
+
+		color, ok := s.colors[stream]
+		if !ok {
+			color = len(s.colors)
+			s.colors[stream] = color
+		}
+		starts = append(starts, streamStart{stream: stream, color: color, since: s.endedAt[stream]})
+	}
+	return starts
+}
+
+// end marks the stream as no longer running, so it can be restarted from at.
+func (s *logStreams) end(stream logStream, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.active, stream)
+	s.endedAt[stream] = at
+}
+
+// logsArgs returns the kubectl logs args for a stream.
+func logsArgs(opts LogsOptions, start streamStart) []string {
+	args := []string{"logs", start.stream.Pod, "-c", start.stream.Container}
+	if opts.Follow {
+		args = append(args, "-f")
+	}
+	if !start.since.IsZero() {
+		// Resume a stream that ended, ie. after a container restart, without
+		// repeating lines already shown
+		args = append(args, fmt.Sprintf("--since-time=%s", start.since.Format(time.RFC3339)))
+	} else if opts.Since != "" {
+		args = append(args, fmt.Sprintf("--since=%s", opts.Since))
+	}
+	return args
+}
+
+// streamPrefix returns the coloured prefix of a stream's lines.
+func streamPrefix(start streamStart) string {
+	return color.New(prefixColors[start.color%len(prefixColors)]).Sprint(start.stream.String()) + " | "
+}
+
+// TailLogs streams logs from every pod and container matching opts
+// concurrently, prefixing each line with the coloured pod and container. When
+// following, new pods are picked up by polling until interrupted.
+func (b *KubeBuilder) TailLogs(opts LogsOptions) error {
+	debugPrintf := opts.DebugPrintf
+	if debugPrintf == nil {
+		debugPrintf = func(context string, format string, args ...any) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	selector := b.Substitute([]string{opts.Selector}, opts.Context, opts.Namespace)[0]
+	if opts.Workload != "" {
+		opts.Workload = b.Substitute([]string{opts.Workload}, opts.Context, opts.Namespace)[0]
+		workloadSelector, err := b.workloadSelector(opts)
+		if err != nil {
+			return err
+		}
+		selector = strings.Trim(strings.Join([]string{selector, workloadSelector}, ","), ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var (
+		outMu   sync.Mutex
+		wg      sync.WaitGroup
+		tracked = newLogStreams()
+	)
+
+	run := func(start streamStart) {
+		kubectlArgs, _ := b.BuildKubectlArgs(opts.Context, opts.Namespace, false, opts.AssumeClusterAdmin, logsArgs(opts, start))
+		if opts.Debug {
+			debugPrintf(opts.Context, "%s %s", Kubectl, strings.Join(kubectlArgs, " "))
+		}
+
+		prefix := streamPrefix(start)
+		stdout := newPrefixWriter(os.Stdout, &outMu, prefix)
+		stdout.filter = opts.filterLine
+		stderr := newPrefixWriter(os.Stderr, &outMu, prefix)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := exec.CommandContext(ctx, Kubectl, kubectlArgs...)
+			c.Stdout = stdout
+			c.Stderr = stderr
+			_ = c.Run()
+			_ = stdout.Flush()
+			_ = stderr.Flush()
+
+			tracked.end(start.stream, time.Now())
+		}()
+	}
+
+	streams, err := b.listLogStreams(opts, selector, true)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 && !opts.Follow {
+		return errors.New("no matching pods found")
+	}
+	if opts.Debug {
+		names := make([]string, 0, len(streams))
+		for _, stream := range streams {
+			names = append(names, stream.String())
+		}
+		debugPrintf(opts.Context, "Tailing %d containers: %s", len(names), strings.Join(names, ", "))
+	}
+	for _, start := range tracked.start(streams) {
+		run(start)
+	}
+
+	if opts.Follow {
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+
+	poll:
+		for {
+			select {
+			case <-ctx.Done():
+				break poll
+			case <-ticker.C:
+				streams, err := b.listLogStreams(opts, selector, false)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+					continue
+				}
+
+				for _, start := range tracked.start(streams) {
+					if start.since.IsZero() && opts.Debug {
+						debugPrintf(opts.Context, "Found new container %s", start.stream)
+					}
+					run(start)
+				}
+			}
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsFilterLine(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     LogsOptions
+		line     string
+		expected bool
+	}{
+		{
+			name:     "No filters",
+			line:     "info hello",
+			expected: true,
+		},
+		{
+			name:     "Grep matches",
+			opts:     LogsOptions{Grep: regexp.MustCompile("ERROR|WARN")},
+			line:     "[ERROR] failed",
+			expected: true,
+		},
+		{
+			name:     "Grep doesn't match",
+			opts:     LogsOptions{Grep: regexp.MustCompile("ERROR|WARN")},
+			line:     "[INFO] ok",
+			expected: false,
+		},
+		{
+			name:     "Exclude matches",
+			opts:     LogsOptions{Exclude: regexp.MustCompile("healthz")},
+			line:     "GET /healthz 200",
+			expected: false,
+		},
+		{
+			name: "Exclude takes precedence over grep",
+			opts: LogsOptions{
+				Grep:    regexp.MustCompile("GET"),
+				Exclude: regexp.MustCompile("healthz"),
+			},
+			line:     "GET /healthz 200",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.opts.filterLine([]byte(tc.line)))
+		})
+	}
+}
+
+const podListFixture = `{"items": [
+	{"metadata": {"name": "web-0"}, "spec": {"containers": [{"name": "app"}, {"name": "sidecar"}]}, "status": {"phase": "Running"}},
+	{"metadata": {"name": "web-1"}, "spec": {"containers": [{"name": "app"}]}, "status": {"phase": "Pending"}},
+	{"metadata": {"name": "web-2"}, "spec": {"containers": [{"name": "app"}]}, "status": {"phase": "Succeeded"}},
+	{"metadata": {"name": "db-0"}, "spec": {"containers": [{"name": "app"}]}, "status": {"phase": "Running"}}
+]}`
+
+func TestPodLogStreams(t *testing.T) {
+	var pods podList
+	assert.NoError(t, json.Unmarshal([]byte(podListFixture), &pods))
+
+	assert.Equal(t, []logStream{
+		{Pod: "web-0", Container: "app"},
+		{Pod: "web-0", Container: "sidecar"},
+		{Pod: "web-2", Container: "app"},
+		{Pod: "db-0", Container: "app"},
+	}, podLogStreams(LogsOptions{}, pods, true))
+
+	// Completed pods are skipped when polling for new pods
+	opts := LogsOptions{PodPattern: regexp.MustCompile("^web"), ContainerPattern: regexp.MustCompile("app")}
+	assert.Equal(t, []logStream{{Pod: "web-0", Container: "app"}}, podLogStreams(opts, pods, false))
+}
+
+func TestLogStreamsStart(t *testing.T) {
+	a := logStream{Pod: "web-0", Container: "app"}
+	b := logStream{Pod: "web-1", Container: "app"}
+	c := logStream{Pod: "web-2", Container: "app"}
+	tracked := newLogStreams()
+
+	assert.Equal(t, []streamStart{
+		{stream: a, color: 0},
+		{stream: b, color: 1},
+	}, tracked.start([]logStream{a, b}))
+
+	// Running streams aren't started again
+	assert.Empty(t, tracked.start([]logStream{a, b}))
+
+	// Ended streams are resumed from when they ended, keeping their colour,
+	// and new pods are started
+	ended := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	tracked.end(a, ended)
+	assert.Equal(t, []streamStart{
+		{stream: a, color: 0, since: ended},
+		{stream: c, color: 2},
+	}, tracked.start([]logStream{a, b, c}))
+}
+
+func TestLogsArgs(t *testing.T) {
+	stream := logStream{Pod: "web-0", Container: "app"}
+	opts := LogsOptions{Since: "5m", Follow: true}
+
+	assert.Equal(t, []string{"logs", "web-0", "-c", "app", "-f", "--since=5m"}, logsArgs(opts, streamStart{stream: stream}))
+
+	ended := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"logs", "web-0", "-c", "app", "-f", "--since-time=2025-07-01T12:00:00Z"}, logsArgs(opts, streamStart{stream: stream, since: ended}))
+}
+
+func TestStreamPrefix(t *testing.T) {
+	stream := logStream{Pod: "web-0", Container: "app"}
+
+	// Streams are coloured by their index, wrapping around the colours
+	assert.Contains(t, streamPrefix(streamStart{stream: stream}), "web-0/app | ")
+	assert.Equal(t, streamPrefix(streamStart{stream: stream, color: 1}), streamPrefix(streamStart{stream: stream, color: 1 + len(prefixColors)}))
+}
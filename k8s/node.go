@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DefaultNodeDebugImage is the image of the debug pod started by
+// `mdcli k8s node --shell`.
+const DefaultNodeDebugImage = "busybox"
+
+type nodeResource struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+}
+
+// NodePod is a pod scheduled on a node.
+type NodePod struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Status struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// ResolveNode returns the name of the node matching query, either by the
+// instance id in label or by node name.
+func (b *KubeBuilder) ResolveNode(context, query, label string) (string, error) {
+	if label != "" {
+		kubectlArgs, _ := b.BuildKubectlArgs(context, "", false, false, []string{"get", "nodes", "-l", fmt.Sprintf("%s=%s", label, query), "-o", "json"})
+		var nodes k8sList[nodeResource]
+		if err := DecodeKubectl(kubectlArgs, &nodes); err != nil {
+			return "", err
+		}
+
+		switch len(nodes.Items) {
+		case 0:
+		case 1:
+			return nodes.Items[0].Metadata.Name, nil
+		default:
+			return "", fmt.Errorf("%d nodes have %s=%s", len(nodes.Items), label, query)
+		}
+	}
+
+	kubectlArgs, _ := b.BuildKubectlArgs(context, "", false, false, []string{"get", "node", query, "-o", "json"})
+	var node nodeResource
+	if err := DecodeKubectl(kubectlArgs, &node); err != nil {
+		return "", fmt.Errorf("no node named %s or with instance id %s: %w", query, query, err)
+	}
+	return node.Metadata.Name, nil
+}
+
+// NodePods returns the pods scheduled on the node, by namespace.
+func (b *KubeBuilder) NodePods(context, node string) (map[string][]NodePod, error) {
+	kubectlArgs, _ := b.BuildKubectlArgs(context, "", true, false, []string{"get", "pods", "--field-selector", "spec.nodeName=" + node, "-o", "json"})
+	var pods k8sList[NodePod]
+	if err := DecodeKubectl(kubectlArgs, &pods); err != nil {
+		return nil, err
+	}
+
+	byNamespace := make(map[string][]NodePod)
+	for _, pod := range pods.Items {
+		byNamespace[pod.Metadata.Namespace] = append(byNamespace[pod.Metadata.Namespace], pod)
+	}
+	return byNamespace, nil
+}
+
+// PrintNodePods writes the pods grouped by namespace, both in name order.
+func PrintNodePods(w io.Writer, byNamespace map[string][]NodePod) {
+	namespaces := make([]string, 0, len(byNamespace))
+	for namespace := range byNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		pods := byNamespace[namespace]
+		sort.Slice(pods, func(i, j int) bool {
+			return pods[i].Metadata.Name < pods[j].Metadata.Name
+		})
+
+		fmt.Fprintf(w, "%s (%d)\n", namespace, len(pods))
+		for _, pod := range pods {
+			fmt.Fprintf(w, "  %s\t%s\n", pod.Metadata.Name, pod.Status.Phase)
+		}
+	}
+}
+
+// NodeDebugArgs are the kubectl args starting a privileged debug pod on the
+// node with the host filesystem mounted at /host. Unlike other edit commands,
+// debug only impersonates cluster-admin here rather than in BuildKubectlArgs.
+func NodeDebugArgs(node, image string, assumeClusterAdmin bool) []string {
+	args := []string{"debug", "node/" + node, "-it", "--profile=sysadmin", "--image=" + image}
+	if assumeClusterAdmin {
+		args = append(args, "--as=compute:cluster-admin")
+	}
+	return args
+}
@@ -0,0 +1,42 @@
+package k8s
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintNodePods(t *testing.T) {
+	var pods []NodePod
+	assert.NoError(t, json.Unmarshal([]byte(`[
+		{"metadata": {"name": "web-1", "namespace": "app"}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "agent-x", "namespace": "kube-system"}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "web-0", "namespace": "app"}, "status": {"phase": "Pending"}}
+	]`), &pods))
+
+	byNamespace := make(map[string][]NodePod)
+	for _, pod := range pods {
+		byNamespace[pod.Metadata.Namespace] = append(byNamespace[pod.Metadata.Namespace], pod)
+	}
+
+	var sb strings.Builder
+	PrintNodePods(&sb, byNamespace)
+	assert.Equal(t, "app (2)\n  web-0\tPending\n  web-1\tRunning\nkube-system (1)\n  agent-x\tRunning\n", sb.String())
+}
+
+func TestNodeDebugArgs(t *testing.T) {
+	builder := NewKubeBuilder()
+	args, _ := builder.BuildKubectlArgs("ctx", "", false, false, NodeDebugArgs("ip-10-0-0-1", "busybox", true))
+	assert.Equal(t, []string{"--context", "ctx", "debug", "node/ip-10-0-0-1", "-it", "--profile=sysadmin", "--image=busybox", "--as=compute:cluster-admin"}, args)
+
+	args, _ = builder.BuildKubectlArgs("ctx", "", false, false, NodeDebugArgs("ip-10-0-0-1", "busybox", false))
+	assert.Equal(t, []string{"--context", "ctx", "debug", "node/ip-10-0-0-1", "-it", "--profile=sysadmin", "--image=busybox"}, args)
+
+	// Other debug commands, ie. through k8s kc, are neither confirmed nor
+	// elevated
+	args, confirm := builder.BuildKubectlArgs("ctx", "", false, true, []string{"debug", "pod/web-0", "-it", "--image=busybox"})
+	assert.False(t, confirm)
+	assert.Equal(t, []string{"--context", "ctx", "debug", "pod/web-0", "-it", "--image=busybox"}, args)
+}
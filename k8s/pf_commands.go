@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+func portForwardCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "pf",
+		Aliases: []string{"port-forward"},
+		Usage:   "Manage background port-forwards",
+		Subcommands: []*cli.Command{
+			pfStartCommand(),
+			pfListCommand(),
+			pfStopCommand(),
+			pfRunCommand(),
+		},
+	}
+}
+
+func pfStartCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "start",
+		Usage:     "Start a background port-forward, reusing a matching one if it is already running",
+		ArgsUsage: "<target> <remote-port>",
+		Flags: append(append(BaseK8sFlags, BaseKctlFlags...),
+			&cli.IntFlag{
+				Name:    "local-port",
+				Aliases: []string{"l"},
+				Value:   0,
+				Usage:   "Local `PORT` to listen on. Defaults to a free port.",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
+
+			if cCtx.NArg() != 2 {
+				return cli.Exit("exactly two arguments <target> <remote-port> must be provided", 1)
+			}
+			target := cCtx.Args().Get(0)
+			remotePort, err := strconv.Atoi(cCtx.Args().Get(1))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("invalid remote port: %v", err), 1)
+			}
+
+			context, namespace, err = ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "")
+			if err != nil {
+				return err
+			}
+
+			context, _, err = ParseContext(context, interactive, "", strict)
+			if err != nil {
+				return err
+			}
+
+			namespace, _, _, err = ParseNamespace(namespace, false, interactive, context, "", strict)
+			if err != nil {
+				return err
+			}
+
+			builder := NewKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return err
+			}
+			pf, err := builder.EnsurePortForward(PortForwardOptions{
+				Context:            context,
+				Namespace:          namespace,
+				Target:             target,
+				RemotePort:         remotePort,
+				LocalPort:          cCtx.Int("local-port"),
+				AssumeClusterAdmin: assumeClusterAdmin,
+			})
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Println(pf.Addr())
+			return nil
+		},
+	}
+}
+
+func pfListCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "ls",
+		Aliases: []string{"list"},
+		Usage:   "List running background port-forwards",
+		Action: func(cCtx *cli.Context) error {
+			pfs, err := ListPortForwards()
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "LOCAL\tCONTEXT\tNAMESPACE\tTARGET\tREMOTE\tPID\tAGE\tREADY")
+			for _, pf := range pfs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%t\n",
+					pf.Addr(), pf.Context, pf.Namespace, pf.Target, pf.RemotePort, pf.PID,
+					time.Since(pf.StartedAt).Round(time.Second), pf.Ready())
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func pfStopCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "stop",
+		Usage:     "Stop background port-forwards by local port or target",
+		ArgsUsage: "[<local-port|target>...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "all",
+				Aliases: []string{"a"},
+				Value:   false,
+				Usage:   "Stop all port-forwards",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			var pfs []PortForward
+			if cCtx.Bool("all") {
+				var err error
+				pfs, err = ListPortForwards()
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			} else {
+				if cCtx.NArg() == 0 {
+					return cli.Exit("at least one port-forward or --all must be provided", 1)
+				}
+				for _, id := range cCtx.Args().Slice() {
+					matches, err := FindPortForwards(id)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					pfs = append(pfs, matches...)
+				}
+			}
+
+			var errs []error
+			for _, pf := range pfs {
+				if err := StopPortForward(pf); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				fmt.Printf("Stopped %s -> %s:%d\n", pf.Addr(), pf.Target, pf.RemotePort)
+			}
+
+			if err := errors.Join(errs...); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			return nil
+		},
+	}
+}
+
+func pfRunCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "run",
+		Usage:     "Supervise a port-forward in the foreground, used internally by start",
+		ArgsUsage: "-- <kubectl-args>...",
+		Hidden:    true,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:     "local-port",
+				Required: true,
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			return SupervisePortForward(cCtx.Int("local-port"), cCtx.Args().Slice())
+		},
+	}
+}
@@ -0,0 +1,355 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+)
+
+const (
+	defaultReadyTimeout = 15 * time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+var portForwardStateFile = []string{"k8s", "port-forwards.json"}
+
+// PortForward is a background port-forward managed by `mdcli k8s pf`.
+type PortForward struct {
+	Context    string    `json:"context"`
+	Namespace  string    `json:"namespace"`
+	Target     string    `json:"target"`
+	RemotePort int       `json:"remotePort"`
+	LocalPort  int       `json:"localPort"`
+	PID        int       `json:"pid"`
+	StartedAt  time.Time `json:"startedAt"`
+}
+
+// ID identifies the port-forward, which is unique by local port.
+func (pf PortForward) ID() string {
+	return strconv.Itoa(pf.LocalPort)
+}
+
+// Addr is the local address the port-forward listens on.
+func (pf PortForward) Addr() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(pf.LocalPort))
+}
+
+// Alive returns whether the supervisor process for the port-forward is running.
+func (pf PortForward) Alive() bool {
+	if pf.PID <= 0 {
+		return false
+	}
+	return syscall.Kill(pf.PID, 0) == nil
+}
+
+// Ready returns whether the local port accepts connections.
+func (pf PortForward) Ready() bool {
+	conn, err := net.DialTimeout("tcp", pf.Addr(), 500*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func (pf PortForward) matches(opts PortForwardOptions) bool {
+	return pf.Context == opts.Context &&
+		pf.Namespace == opts.Namespace &&
+		pf.Target == opts.Target &&
+		pf.RemotePort == opts.RemotePort &&
+		(opts.LocalPort == 0 || pf.LocalPort == opts.LocalPort)
+}
+
+// PortForwardOptions configures a managed port-forward.
+type PortForwardOptions struct {
+	Context    string
+	Namespace  string
+	Target     string
+	RemotePort int
+	// Local port to listen on, a free port is allocated if 0
+	LocalPort          int
+	AssumeClusterAdmin bool
+	// How long to wait for the local port to accept connections
+	ReadyTimeout time.Duration
+}
+
+func portForwardStatePath() (string, error) {
+	return state.Path(portForwardStateFile...)
+}
+
+func portForwardLogPath(localPort int) (string, error) {
+	return state.Path("k8s", "port-forwards", fmt.Sprintf("%d.log", localPort))
+}
+
+// updatePortForwards applies fn to the stored port-forwards under lock,
+// dropping any whose supervisor is no longer running.
+func updatePortForwards(fn func([]PortForward) []PortForward) ([]PortForward, error) {
+	path, err := portForwardStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var pfs []PortForward
+	err = state.WithLock(path, func() error {
+		stored := make([]PortForward, 0)
+		if err := state.ReadJSON(path, &stored); err != nil {
+			return err
+		}
+
+		pfs = make([]PortForward, 0, len(stored))
+		for _, pf := range stored {
+			if pf.Alive() {
+				pfs = append(pfs, pf)
+			}
+		}
+
+		if fn != nil {
+			pfs = fn(pfs)
+		}
+		return state.WriteJSON(path, pfs)
+	})
+	return pfs, err
+}
+
+// ListPortForwards returns the running managed port-forwards.
+func ListPortForwards() ([]PortForward, error) {
+	return updatePortForwards(nil)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (b *KubeBuilder) resolvePortForwardOptions(opts PortForwardOptions) PortForwardOptions {
+	opts.Target = b.Substitute([]string{opts.Target}, opts.Context, opts.Namespace)[0]
+	if opts.ReadyTimeout == 0 {
+		opts.ReadyTimeout = defaultReadyTimeout
+	}
+	return opts
+}
+
+// EnsurePortForward returns a running port-forward matching opts, starting one
+// if none exists.
+func (b *KubeBuilder) EnsurePortForward(opts PortForwardOptions) (PortForward, error) {
+	opts = b.resolvePortForwardOptions(opts)
+
+	pfs, err := ListPortForwards()
+	if err != nil {
+		return PortForward{}, err
+	}
+	for _, pf := range pfs {
+		if pf.matches(opts) && waitReady(pf, opts.ReadyTimeout) == nil {
+			return pf, nil
+		}
+	}
+
+	return b.startPortForward(opts)
+}
+
+// StartPortForward starts a port-forward in a detached supervisor process,
+// which reconnects if the underlying kubectl port-forward exits. It returns
+// once the local port accepts connections.
+func (b *KubeBuilder) StartPortForward(opts PortForwardOptions) (PortForward, error) {
+	return b.startPortForward(b.resolvePortForwardOptions(opts))
+}
+
+// portForwardKubectlArgs returns the kubectl args for opts already resolved.
+// The target is escaped so BuildKubectlArgs doesn't substitute it again.
+func (b *KubeBuilder) portForwardKubectlArgs(opts PortForwardOptions) []string {
+	args, _ := b.BuildKubectlArgs(opts.Context, opts.Namespace, false, opts.AssumeClusterAdmin, []string{
+		"port-forward", strings.ReplaceAll(opts.Target, "%", "%%"), fmt.Sprintf("%d:%d", opts.LocalPort, opts.RemotePort),
+	})
+	return args
+}
+
+// startPortForward starts a port-forward for opts already resolved.
+func (b *KubeBuilder) startPortForward(opts PortForwardOptions) (PortForward, error) {
+	var err error
+	if opts.LocalPort == 0 {
+		opts.LocalPort, err = freePort()
+		if err != nil {
+			return PortForward{}, fmt.Errorf("failed to allocate local port: %w", err)
+		}
+	}
+
+	kubectlArgs := b.portForwardKubectlArgs(opts)
+
+	self, err := os.Executable()
+	if err != nil {
+		return PortForward{}, err
+	}
+
+	logPath, err := portForwardLogPath(opts.LocalPort)
+	if err != nil {
+		return PortForward{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return PortForward{}, err
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return PortForward{}, err
+	}
+	defer func() {
+		_ = logFile.Close()
+	}()
+
+	args := append([]string{"k8s", "pf", "run", "--local-port", strconv.Itoa(opts.LocalPort), "--"}, kubectlArgs...)
+	c := exec.Command(self, args...)
+	c.Stdout = logFile
+	c.Stderr = logFile
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := c.Start(); err != nil {
+		return PortForward{}, fmt.Errorf("failed to start port-forward: %w", err)
+	}
+
+	pf := PortForward{
+		Context:    opts.Context,
+		Namespace:  opts.Namespace,
+		Target:     opts.Target,
+		RemotePort: opts.RemotePort,
+		LocalPort:  opts.LocalPort,
+		PID:        c.Process.Pid,
+		StartedAt:  time.Now(),
+	}
+	_, err = updatePortForwards(func(pfs []PortForward) []PortForward {
+		return append(pfs, pf)
+	})
+	if err != nil {
+		_ = c.Process.Kill()
+		return PortForward{}, err
+	}
+
+	// Reap the supervisor if it exits while this process is still running
+	go func() {
+		_ = c.Wait()
+	}()
+
+	if err := waitReady(pf, opts.ReadyTimeout); err != nil {
+		_ = StopPortForward(pf)
+		return PortForward{}, fmt.Errorf("%w, see %s", err, logPath)
+	}
+	return pf, nil
+}
+
+func waitReady(pf PortForward, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if pf.Ready() {
+			return nil
+		}
+		if !pf.Alive() {
+			return fmt.Errorf("port-forward to %s exited", pf.Target)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("port-forward to %s not ready after %s", pf.Target, timeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// StopPortForward stops the supervisor for pf and removes it from the state.
+func StopPortForward(pf PortForward) error {
+	if pf.Alive() {
+		if err := syscall.Kill(pf.PID, syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to stop port-forward %s: %w", pf.ID(), err)
+		}
+	}
+
+	_, err := updatePortForwards(func(pfs []PortForward) []PortForward {
+		return slices.DeleteFunc(pfs, func(other PortForward) bool {
+			return other.LocalPort == pf.LocalPort
+		})
+	})
+	return err
+}
+
+// SupervisePortForward runs kubectl with kubectlArgs until terminated,
+// restarting it with backoff whenever it exits.
+func SupervisePortForward(localPort int, kubectlArgs []string) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	cleanup := func() error {
+		_, err := updatePortForwards(func(pfs []PortForward) []PortForward {
+			return slices.DeleteFunc(pfs, func(pf PortForward) bool {
+				return pf.LocalPort == localPort && pf.PID == os.Getpid()
+			})
+		})
+		return err
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		c := exec.Command(Kubectl, kubectlArgs...)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Start(); err != nil {
+			_ = cleanup()
+			return err
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Wait()
+		}()
+
+		select {
+		case <-sigs:
+			_ = c.Process.Kill()
+			<-done
+			return cleanup()
+		case err := <-done:
+			if time.Since(started) > maxReconnectBackoff {
+				backoff = time.Second
+			}
+			fmt.Fprintf(os.Stderr, "%s port-forward exited (%v), reconnecting in %s\n", time.Now().Format(time.RFC3339), err, backoff)
+		}
+
+		select {
+		case <-sigs:
+			return cleanup()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// FindPortForwards returns the running port-forwards matching id, which is
+// either the local port or the target.
+func FindPortForwards(id string) ([]PortForward, error) {
+	pfs, err := ListPortForwards()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]PortForward, 0)
+	for _, pf := range pfs {
+		if pf.ID() == id || pf.Target == id {
+			matches = append(matches, pf)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, errors.New("no port-forward found for " + id)
+	}
+	return matches, nil
+}
@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortForwardMatches(t *testing.T) {
+	pf := PortForward{
+		Context:    "my-context",
+		Namespace:  "my-namespace",
+		Target:     "svc/my-svc",
+		RemotePort: 4000,
+		LocalPort:  4010,
+	}
+
+	opts := PortForwardOptions{
+		Context:    "my-context",
+		Namespace:  "my-namespace",
+		Target:     "svc/my-svc",
+		RemotePort: 4000,
+	}
+	assert.True(t, pf.matches(opts))
+
+	opts.LocalPort = 4010
+	assert.True(t, pf.matches(opts))
+
+	opts.LocalPort = 4011
+	assert.False(t, pf.matches(opts))
+
+	opts.LocalPort = 0
+	opts.RemotePort = 2379
+	assert.False(t, pf.matches(opts))
+}
+
+func TestListPortForwardsPrunesExited(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	path, err := portForwardStatePath()
+	assert.NoError(t, err)
+
+	running := PortForward{Target: "running", LocalPort: 4010, PID: os.Getpid(), StartedAt: time.Now()}
+	exited := PortForward{Target: "exited", LocalPort: 4011, PID: 0, StartedAt: time.Now()}
+	assert.NoError(t, state.WriteJSON(path, []PortForward{running, exited}))
+
+	pfs, err := ListPortForwards()
+	assert.NoError(t, err)
+	assert.Len(t, pfs, 1)
+	assert.Equal(t, "running", pfs[0].Target)
+
+	matches, err := FindPortForwards("4010")
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	_, err = FindPortForwards("exited")
+	assert.Error(t, err)
+}
+
+func TestPortForwardSubstitutesTargetOnce(t *testing.T) {
+	builder := NewKubeBuilderWithSubstitutions([]Substitution{{
+		Aliases: []string{"tc"},
+		Generate: func(context, namespace string) (string, error) {
+			return "merge", nil
+		},
+	}})
+
+	opts := builder.resolvePortForwardOptions(PortForwardOptions{
+		Context:    "my-context",
+		Namespace:  "my-namespace",
+		Target:     "svc/%tc-pd-%%tc",
+		RemotePort: 2379,
+		LocalPort:  4010,
+	})
+	assert.Equal(t, "svc/merge-pd-%tc", opts.Target)
+	assert.Equal(t, []string{
+		"--context", "my-context", "--namespace", "my-namespace",
+		"port-forward", "svc/merge-pd-%tc", "4010:2379",
+	}, builder.portForwardKubectlArgs(opts))
+}
@@ -0,0 +1,270 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// Number of targets offered by --recent
+	recentTargetCount = 10
+	maxRecentUsages   = 500
+	recentUsageTTL    = 90 * 24 * time.Hour
+)
+
+var recentStateFile = []string{"k8s", "recent.json"}
+
+var RecentFlag = &cli.BoolFlag{
+	Name:  "recent",
+	Value: false,
+	Usage: "Pick from the most recently used contexts and namespaces instead of all of them",
+}
+
+// recentUsage tracks how often and how recently a context and namespace were
+// used.
+type recentUsage struct {
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace"`
+	Count     int       `json:"count"`
+	LastUsed  time.Time `json:"lastUsed"`
+}
+
+// score weights the usage count by how recently it was last used, so targets
+// used often in the past don't outrank ones in use now.
+func (u recentUsage) score(now time.Time) float64 {
+	age := now.Sub(u.LastUsed)
+	var weight float64
+	switch {
+	case age < time.Hour:
+		weight = 8
+	case age < 24*time.Hour:
+		weight = 4
+	case age < 7*24*time.Hour:
+		weight = 2
+	case age < 30*24*time.Hour:
+		weight = 1
+	default:
+		weight = 0.5
+	}
+	return float64(u.Count) * weight
+}
+
+func loadRecentUsages() ([]recentUsage, error) {
+	path, err := state.Path(recentStateFile...)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make([]recentUsage, 0)
+	if err := state.ReadJSON(path, &usages); err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
+
+// RecordRecentTarget records a use of context and namespace for ordering the
+// pickers. It's skipped if another process is recording, and failures are only
+// warned about since the history is best effort.
+func RecordRecentTarget(context, namespace string) {
+	if context == "" || namespace == "" {
+		return
+	}
+
+	path, err := state.Path(recentStateFile...)
+	if err == nil {
+		err = state.TryWithLock(path, func() error {
+			usages := make([]recentUsage, 0)
+			if err := state.ReadJSON(path, &usages); err != nil {
+				return err
+			}
+
+			now := time.Now()
+			usages = recordUsage(usages, context, namespace, now)
+			return state.WriteJSON(path, usages)
+		})
+	}
+	if err != nil && !errors.Is(err, state.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record recent target: %v\n", err)
+	}
+}
+
+// recordUsage increments the usage of context and namespace, dropping expired
+// usages and keeping at most maxRecentUsages of the most recent.
+func recordUsage(usages []recentUsage, context, namespace string, now time.Time) []recentUsage {
+	idx := slices.IndexFunc(usages, func(u recentUsage) bool {
+		return u.Context == context && u.Namespace == namespace
+	})
+	if idx < 0 {
+		usages = append(usages, recentUsage{Context: context, Namespace: namespace})
+		idx = len(usages) - 1
+	}
+	usages[idx].Count++
+	usages[idx].LastUsed = now
+
+	usages = slices.DeleteFunc(usages, func(u recentUsage) bool {
+		return now.Sub(u.LastUsed) > recentUsageTTL
+	})
+	sortByLastUsed(usages)
+	if len(usages) > maxRecentUsages {
+		usages = usages[:maxRecentUsages]
+	}
+	return usages
+}
+
+func sortByLastUsed(usages []recentUsage) {
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].LastUsed.After(usages[j].LastUsed)
+	})
+}
+
+// pickerItem is a line in an fzf picker. Recent items are marked.
+type pickerItem struct {
+	Name   string
+	Recent bool
+}
+
+// rankItems orders names by their score, highest first, followed by the
+// unused names in their original order.
+func rankItems(names []string, scores map[string]float64) []pickerItem {
+	ranked := slices.Clone(names)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+
+	items := make([]pickerItem, 0, len(ranked))
+	for _, name := range ranked {
+		items = append(items, pickerItem{Name: name, Recent: scores[name] > 0})
+	}
+	return items
+}
+
+func contextScores(usages []recentUsage, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, u := range usages {
+		scores[u.Context] += u.score(now)
+	}
+	return scores
+}
+
+func namespaceScores(usages []recentUsage, context string, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, u := range usages {
+		if u.Context == context {
+			scores[u.Namespace] += u.score(now)
+		}
+	}
+	return scores
+}
+
+func filterNames(names []string, pattern string) ([]string, error) {
+	if pattern == "" {
+		return names, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return !re.MatchString(name)
+	}), nil
+}
+
+// pick runs fzf over lines, which are tab separated columns with a leading
+// marker column, and returns the remaining columns of the selected line.
+func pick(prompt string, lines []string) ([]string, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("nothing to select from")
+	}
+
+	c := exec.Command("fzf", "--ansi", "--no-preview", "--no-sort", "--delimiter", "\t", "--nth", "2..", "--prompt", prompt)
+	c.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	c.Stderr = os.Stderr
+	selected, err := cmd.CaptureCmd(*c)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(strings.TrimRight(selected, "\n"), "\t")
+	if len(fields) < 2 || fields[1] == "" {
+		return nil, errors.New("nothing selected")
+	}
+	return fields[1:], nil
+}
+
+func pickerLines(items []pickerItem) []string {
+	mark := color.New(color.FgYellow).Sprint("*")
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Recent {
+			lines = append(lines, fmt.Sprintf("%s\t%s", mark, item.Name))
+		} else {
+			lines = append(lines, fmt.Sprintf(" \t%s", item.Name))
+		}
+	}
+	return lines
+}
+
+// PickRecentTarget offers the most recently used contexts and namespaces,
+// whose context matches pattern if one is given.
+func PickRecentTarget(pattern string) (string, string, error) {
+	usages, err := loadRecentUsages()
+	if err != nil {
+		return "", "", err
+	}
+
+	sortByLastUsed(usages)
+	lines := make([]string, 0, recentTargetCount)
+	for _, u := range usages {
+		if !matchesPattern(u.Context, pattern) {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf(" \t%s\t%s", u.Context, u.Namespace))
+		if len(lines) == recentTargetCount {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return "", "", errors.New("no recent contexts and namespaces")
+	}
+
+	fields, err := pick("Select recent target> ", lines)
+	if err != nil {
+		return "", "", err
+	}
+	if len(fields) != 2 {
+		return "", "", errors.New("no target selected")
+	}
+	return fields[0], fields[1], nil
+}
+
+// ParseRecentTarget picks a recent context and namespace with --recent if the
+// context isn't set, keeping the namespace if it is set.
+func ParseRecentTarget(context, namespace string, recent bool, pattern string) (string, string, error) {
+	if !recent || context != "" {
+		return context, namespace, nil
+	}
+
+	pickedContext, pickedNamespace, err := PickRecentTarget(pattern)
+	if err != nil {
+		return "", "", err
+	}
+	if namespace == "" {
+		namespace = pickedNamespace
+	}
+	RecordRecentTarget(pickedContext, namespace)
+	return pickedContext, namespace, nil
+}
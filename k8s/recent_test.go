@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordUsage(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	usages := []recentUsage{
+		{Context: "a", Namespace: "x", Count: 3, LastUsed: now.Add(-time.Hour)},
+		{Context: "b", Namespace: "y", Count: 1, LastUsed: now.Add(-100 * 24 * time.Hour)},
+	}
+
+	usages = recordUsage(usages, "c", "z", now)
+	usages = recordUsage(usages, "a", "x", now.Add(time.Minute))
+
+	assert.Equal(t, []recentUsage{
+		{Context: "a", Namespace: "x", Count: 4, LastUsed: now.Add(time.Minute)},
+		{Context: "c", Namespace: "z", Count: 1, LastUsed: now},
+	}, usages)
+}
+
+func TestRankItems(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	usages := []recentUsage{
+		{Context: "a", Namespace: "x", Count: 10, LastUsed: now.Add(-60 * 24 * time.Hour)},
+		{Context: "b", Namespace: "y", Count: 2, LastUsed: now.Add(-time.Minute)},
+		{Context: "b", Namespace: "z", Count: 1, LastUsed: now.Add(-2 * time.Hour)},
+	}
+
+	// Recent use outweighs older, more frequent use
+	contexts := rankItems([]string{"a", "b", "c", "d"}, contextScores(usages, now))
+	assert.Equal(t, []pickerItem{
+		{Name: "b", Recent: true},
+		{Name: "a", Recent: true},
+		{Name: "c"},
+		{Name: "d"},
+	}, contexts)
+
+	namespaces := rankItems([]string{"w", "x", "y", "z"}, namespaceScores(usages, "b", now))
+	assert.Equal(t, []pickerItem{
+		{Name: "y", Recent: true},
+		{Name: "z", Recent: true},
+		{Name: "w"},
+		{Name: "x"},
+	}, namespaces)
+}
+
+func TestRecordRecentTarget(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+
+	// Targets given by flags or sticky state aren't recorded
+	_, _, _, err := ParseNamespace("my-namespace", false, false, "my-context", "", true)
+	assert.NoError(t, err)
+	usages, err := loadRecentUsages()
+	assert.NoError(t, err)
+	assert.Empty(t, usages)
+
+	RecordRecentTarget("my-context", "my-namespace")
+	usages, err = loadRecentUsages()
+	assert.NoError(t, err)
+	assert.Len(t, usages, 1)
+
+	// Recording is skipped rather than waiting on another process
+	path, err := state.Path(recentStateFile...)
+	assert.NoError(t, err)
+	assert.NoError(t, state.WithLock(path, func() error {
+		RecordRecentTarget("other-context", "other-namespace")
+		return nil
+	}))
+	usages, err = loadRecentUsages()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usages[0].Count)
+	assert.Len(t, usages, 1)
+}
+
+func TestParseRecentTarget(t *testing.T) {
+	// Without --recent or with a context, the target is unchanged
+	context, namespace, err := ParseRecentTarget("", "my-namespace", false, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", context)
+	assert.Equal(t, "my-namespace", namespace)
+
+	context, namespace, err = ParseRecentTarget("my-context", "", true, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-context", context)
+	assert.Equal(t, "", namespace)
+}
@@ -0,0 +1,518 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"github.com/peterh/liner"
+)
+
+const replHelp = `Enter kubectl commands without the kubectl prefix, ie. get pods.
+
+Meta-commands:
+  :ns <namespace>          Switch namespace
+  :ctx <context> [ns]      Switch context, keeping the namespace unless given
+  :help                    Show this help
+  :q, :quit, :exit         Exit the shell`
+
+// replVerbs are completed as the first word of a line.
+var replVerbs = []string{
+	"annotate", "api-resources", "apply", "attach", "auth", "cp", "create",
+	"debug", "delete", "describe", "edit", "events", "exec", "explain", "get",
+	"label", "logs", "patch", "port-forward", "rollout", "scale", "top",
+}
+
+var replMetaCommands = []string{":ctx", ":exit", ":help", ":ns", ":q", ":quit"}
+
+// replResourceVerbs take a resource type and name as their first arguments.
+var replResourceVerbs = map[string]struct{}{
+	"annotate": {},
+	"delete":   {},
+	"describe": {},
+	"edit":     {},
+	"get":      {},
+	"label":    {},
+	"patch":    {},
+	"scale":    {},
+}
+
+// replValueFlags are kubectl flags whose value is the following word, so it
+// isn't mistaken for a resource when completing.
+var replValueFlags = map[string]struct{}{
+	"-c":               {},
+	"--container":      {},
+	"-f":               {},
+	"--filename":       {},
+	"--field-selector": {},
+	"-l":               {},
+	"--selector":       {},
+	"-o":               {},
+	"--output":         {},
+}
+
+// ReplOptions configures RunRepl.
+type ReplOptions struct {
+	Context   string
+	Namespace string
+	// Skip confirmation prompts for edit commands
+	Confirmed bool
+	Debug     bool
+	// Shell started by exec without a command. Detected from Shells if empty.
+	Shell  string
+	Shells []string
+	// AssumeClusterAdmin returns whether to impersonate cluster-admin for
+	// edit commands in context
+	AssumeClusterAdmin func(context string) bool
+	// Resolve maps the context and namespace given to :ctx and :ns, which may
+	// be aliases, to full names
+	Resolve     func(context, namespace string) (string, string)
+	DebugPrintf func(context string, format string, args ...any)
+}
+
+type repl struct {
+	builder   *KubeBuilder
+	opts      ReplOptions
+	line      *liner.State
+	context   string
+	namespace string
+	// Completion candidates cached for the session, keyed by context and
+	// namespace
+	resourceTypes map[string][]string
+	names         map[string][]string
+}
+
+// RunRepl runs a line-edited kubectl shell against opts.Context and
+// opts.Namespace until EOF or :quit. Each line is built with
+// BuildKubectlArgs, so substitutions, confirmations and impersonation apply as
+// they do for `mdcli k8s kubectl`.
+func (b *KubeBuilder) RunRepl(opts ReplOptions) error {
+	if opts.Resolve == nil {
+		opts.Resolve = func(context, namespace string) (string, string) {
+			return context, namespace
+		}
+	}
+	if opts.AssumeClusterAdmin == nil {
+		opts.AssumeClusterAdmin = func(string) bool {
+			return false
+		}
+	}
+	if opts.DebugPrintf == nil {
+		opts.DebugPrintf = func(context string, format string, args ...any) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	r := &repl{
+		builder:       b,
+		opts:          opts,
+		line:          liner.NewLiner(),
+		context:       opts.Context,
+		namespace:     opts.Namespace,
+		resourceTypes: make(map[string][]string),
+		names:         make(map[string][]string),
+	}
+	defer func() {
+		_ = r.line.Close()
+	}()
+	r.line.SetCtrlCAborts(true)
+	r.line.SetTabCompletionStyle(liner.TabPrints)
+	r.line.SetWordCompleter(r.complete)
+	r.loadHistory()
+
+	for {
+		input, err := r.line.Prompt(r.prompt())
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		} else if errors.Is(err, io.EOF) {
+			fmt.Println()
+			break
+		} else if err != nil {
+			return err
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		r.line.AppendHistory(input)
+
+		if strings.HasPrefix(input, ":") {
+			done, err := r.runMeta(input)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			if done {
+				break
+			}
+			continue
+		}
+
+		if err := r.run(input); err != nil {
+			// kubectl reports its own errors
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+	}
+
+	return r.saveHistory()
+}
+
+func (r *repl) prompt() string {
+	return fmt.Sprintf("%s/%s> ", r.context, r.namespace)
+}
+
+// historyPath is the history file for the current context.
+func (r *repl) historyPath() (string, error) {
+	name := strings.ReplaceAll(r.context, string(os.PathSeparator), "_")
+	if name == "" {
+		name = "default"
+	}
+	return state.Path("k8s", "history", name)
+}
+
+func (r *repl) loadHistory() {
+	path, err := r.historyPath()
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	_, _ = r.line.ReadHistory(f)
+}
+
+func (r *repl) saveHistory() error {
+	path, err := r.historyPath()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := r.line.WriteHistory(&buf); err != nil {
+		return err
+	}
+	if err := state.WriteFileAtomic(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// switchTarget changes the context and namespace, swapping in the history of
+// the new context.
+func (r *repl) switchTarget(context, namespace string) {
+	if context != r.context {
+		if err := r.saveHistory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		r.line.ClearHistory()
+		r.context = context
+		r.loadHistory()
+	}
+	r.namespace = namespace
+	RecordRecentTarget(r.context, r.namespace)
+}
+
+// runMeta runs a meta-command, returning whether the shell should exit.
+func (r *repl) runMeta(input string) (bool, error) {
+	fields, err := splitLine(input)
+	if err != nil {
+		return false, err
+	}
+
+	switch fields[0] {
+	case ":q", ":quit", ":exit":
+		return true, nil
+	case ":help":
+		fmt.Println(replHelp)
+	case ":ns":
+		if len(fields) != 2 {
+			return false, errors.New("usage: :ns <namespace>")
+		}
+		r.switchTarget(r.opts.Resolve(r.context, fields[1]))
+	case ":ctx":
+		if len(fields) < 2 || len(fields) > 3 {
+			return false, errors.New("usage: :ctx <context> [namespace]")
+		}
+		namespace := r.namespace
+		if len(fields) == 3 {
+			namespace = fields[2]
+		}
+		r.switchTarget(r.opts.Resolve(fields[1], namespace))
+	default:
+		return false, fmt.Errorf("unknown command '%s', see :help", fields[0])
+	}
+	return false, nil
+}
+
+// run builds and runs the kubectl command on the line.
+func (r *repl) run(input string) error {
+	args, err := splitLine(input)
+	if err != nil {
+		return err
+	}
+	if args[0] == Kubectl {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	r.builder.Shell = r.opts.Shell
+	if r.builder.Shell == "" && r.builder.IsInteractiveExec(args) {
+		r.builder.Shell, err = r.builder.ResolveShell(r.context, r.namespace, r.opts.AssumeClusterAdmin(r.context), args, r.opts.Shells)
+		if err != nil {
+			return err
+		}
+	}
+
+	kubectlArgs, confirm := r.builder.BuildKubectlArgs(r.context, r.namespace, false, r.opts.AssumeClusterAdmin(r.context), slices.Clone(args))
+	needsConfirm := confirm && !r.opts.Confirmed
+	if r.opts.Debug || needsConfirm {
+		r.opts.DebugPrintf(r.context, "%s %s", Kubectl, strings.Join(kubectlArgs, " "))
+	}
+	if needsConfirm && !r.confirm("Do you want to execute the above command?") {
+		fmt.Println("Command canceled")
+		return nil
+	}
+
+	c := exec.Command(Kubectl, kubectlArgs...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+// confirm prompts with the line editor rather than reading stdin directly, so
+// buffered input isn't lost between prompts.
+func (r *repl) confirm(s string) bool {
+	for range 3 {
+		response, err := r.line.Prompt(fmt.Sprintf("%s [y/n]: ", s))
+		if err != nil {
+			return false
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+	return false
+}
+
+// splitLine splits a line into words like a shell, handling single and double
+// quotes and backslash escapes.
+func splitLine(line string) ([]string, error) {
+	words := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(ch)
+			}
+		case quote == '"':
+			if ch == '"' {
+				quote = 0
+			} else if ch == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`, runes[i+1]) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inWord = true
+		case ch == '\\':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+		case ch == ' ' || ch == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return words, nil
+}
+
+// complete completes the word under the cursor.
+func (r *repl) complete(line string, pos int) (string, []string, string) {
+	before := line[:pos]
+	idx := strings.LastIndexAny(before, " \t")
+	head, word := before[:idx+1], before[idx+1:]
+
+	completions := make([]string, 0)
+	for _, candidate := range r.candidates(strings.Fields(head), word) {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+	return head, completions, line[pos:]
+}
+
+// candidates returns the possible completions for the word following tokens.
+func (r *repl) candidates(tokens []string, word string) []string {
+	if len(tokens) == 0 {
+		return append(slices.Clone(replVerbs), replMetaCommands...)
+	}
+	if strings.HasPrefix(word, "-") {
+		return nil
+	}
+
+	switch tokens[0] {
+	case ":ns":
+		if len(tokens) == 1 {
+			namespaces, _ := listNamespaces(r.context)
+			return namespaces
+		}
+		return nil
+	case ":ctx":
+		if len(tokens) == 1 {
+			contexts, _ := listContexts()
+			return contexts
+		}
+		return nil
+	}
+
+	positional := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if _, ok := replValueFlags[tokens[i]]; ok {
+			i++
+		} else if !strings.HasPrefix(tokens[i], "-") {
+			positional = append(positional, tokens[i])
+		}
+	}
+	if len(positional) > 0 && positional[0] == Kubectl {
+		positional = positional[1:]
+	}
+	if len(positional) == 0 {
+		return slices.Clone(replVerbs)
+	}
+
+	verb := positional[0]
+	_, isResourceVerb := replResourceVerbs[verb]
+	switch len(positional) {
+	case 1:
+		if isResourceModifiableCmd(verb) {
+			candidates := r.resourceNames("pods")
+			for resource := range modifiableResources {
+				candidates = append(candidates, resource)
+			}
+			return candidates
+		} else if isResourceVerb || verb == "explain" {
+			return r.apiResources()
+		}
+	case 2:
+		if isResourceVerb || (isResourceModifiableCmd(verb) && isModifiableResource(positional[1])) {
+			return r.resourceNames(positional[1])
+		}
+	}
+	return nil
+}
+
+func (r *repl) capture(args ...string) ([]byte, error) {
+	kubectlArgs := make([]string, 0)
+	if r.context != "" {
+		kubectlArgs = append(kubectlArgs, "--context", r.context)
+	}
+	if r.namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", r.namespace)
+	}
+
+	c := exec.Command(Kubectl, append(kubectlArgs, args...)...)
+	c.Stderr = io.Discard
+	return c.Output()
+}
+
+// apiResources returns the resource types of the current context, both
+// qualified, ie. deployments.apps, and unqualified.
+func (r *repl) apiResources() []string {
+	if types, ok := r.resourceTypes[r.context]; ok {
+		return types
+	}
+
+	output, err := r.capture("api-resources", "-o", "name")
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range strings.Fields(string(output)) {
+		seen[name] = struct{}{}
+		short, _, _ := strings.Cut(name, ".")
+		seen[short] = struct{}{}
+	}
+
+	types := make([]string, 0, len(seen))
+	for name := range seen {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	r.resourceTypes[r.context] = types
+	return types
+}
+
+// resourceNames returns the names of resources of the type in the current
+// namespace.
+func (r *repl) resourceNames(resourceType string) []string {
+	key := strings.Join([]string{r.context, r.namespace, resourceType}, "/")
+	if names, ok := r.names[key]; ok {
+		return slices.Clone(names)
+	}
+
+	output, err := r.capture("get", resourceType, "-o", "name")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0)
+	for _, name := range strings.Fields(string(output)) {
+		if _, after, ok := strings.Cut(name, "/"); ok {
+			name = after
+		}
+		names = append(names, name)
+	}
+	r.names[key] = names
+	return slices.Clone(names)
+}
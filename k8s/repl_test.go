@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLine(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "Words",
+			line:     "get pods  -l app=foo",
+			expected: []string{"get", "pods", "-l", "app=foo"},
+		},
+		{
+			name:     "Single quotes",
+			line:     `exec foo -- sh -c 'echo "$HOME"'`,
+			expected: []string{"exec", "foo", "--", "sh", "-c", `echo "$HOME"`},
+		},
+		{
+			name:     "Double quotes with escapes",
+			line:     `annotate pod foo note="a \"b\" c"`,
+			expected: []string{"annotate", "pod", "foo", `note=a "b" c`},
+		},
+		{
+			name:     "Backslash escape",
+			line:     `get pod a\ b`,
+			expected: []string{"get", "pod", "a b"},
+		},
+		{
+			name:     "Empty quotes",
+			line:     `label pod foo x=''`,
+			expected: []string{"label", "pod", "foo", "x="},
+		},
+		{
+			name:    "Unterminated quote",
+			line:    `get pod 'foo`,
+			wantErr: true,
+		},
+		{
+			name:    "Empty",
+			line:    "   ",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			words, err := splitLine(tc.line)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, words)
+		})
+	}
+}
+
+func TestReplComplete(t *testing.T) {
+	r := &repl{
+		context:   "ctx",
+		namespace: "ns",
+		resourceTypes: map[string][]string{
+			"ctx": {"configmaps", "deployments", "deployments.apps", "pods"},
+		},
+		names: map[string][]string{
+			"ctx/ns/pods":        {"web-0", "web-1", "worker-0"},
+			"ctx/ns/deploy":      {"web"},
+			"ctx/ns/deployments": {"web"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		line     string
+		head     string
+		expected []string
+	}{
+		{
+			name:     "Verb",
+			line:     "desc",
+			head:     "",
+			expected: []string{"describe"},
+		},
+		{
+			name:     "Meta-command",
+			line:     ":q",
+			head:     "",
+			expected: []string{":q", ":quit"},
+		},
+		{
+			name:     "Resource type",
+			line:     "get dep",
+			head:     "get ",
+			expected: []string{"deployments", "deployments.apps"},
+		},
+		{
+			name:     "Resource name",
+			line:     "describe pods web",
+			head:     "describe pods ",
+			expected: []string{"web-0", "web-1"},
+		},
+		{
+			name:     "Resource name after flags",
+			line:     "get -o yaml deployments w",
+			head:     "get -o yaml deployments ",
+			expected: []string{"web"},
+		},
+		{
+			name:     "Pod for logs",
+			line:     "logs wo",
+			head:     "logs ",
+			expected: []string{"worker-0"},
+		},
+		{
+			name:     "Workload for exec",
+			line:     "exec deploy w",
+			head:     "exec deploy ",
+			expected: []string{"web"},
+		},
+		{
+			name:     "Flags aren't completed",
+			line:     "get pods --w",
+			head:     "get pods ",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			head, completions, tail := r.complete(tc.line, len(tc.line))
+			assert.Equal(t, tc.head, head)
+			assert.ElementsMatch(t, tc.expected, completions)
+			assert.Equal(t, "", tail)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SecretOutputEnv    = "env"
+	SecretOutputDotenv = "dotenv"
+	SecretOutputJSON   = "json"
+)
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+type secret struct {
+	Data map[string]string `json:"data"`
+}
+
+// GetSecret fetches the secret and returns its base64-decoded data by key.
+func (b *KubeBuilder) GetSecret(context, namespace, name string, assumeClusterAdmin bool) (map[string][]byte, error) {
+	kubectlArgs, _ := b.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, []string{"get", "secret", name, "-o", "json"})
+
+	var s secret
+	if err := DecodeKubectl(kubectlArgs, &s); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(s.Data))
+	for key, value := range s.Data {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key '%s' of secret %s: %w", key, name, err)
+		}
+		data[key] = decoded
+	}
+	return data, nil
+}
+
+// SecretKey returns the decoded value of key in the secret.
+func (b *KubeBuilder) SecretKey(context, namespace, name, key string, assumeClusterAdmin bool) ([]byte, error) {
+	data, err := b.GetSecret(context, namespace, name, assumeClusterAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key '%s'", name, key)
+	}
+	return value, nil
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envName converts a secret key, ie. tls.crt, to an environment variable name.
+func envName(key string) string {
+	name := strings.ToUpper(invalidEnvChars.ReplaceAllString(key, "_"))
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func dotenvQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// FormatSecret formats the secret data. Without an output format, the value of
+// key is returned as is, or the keys are listed if key is empty. Otherwise all
+// keys, or only key if set, are formatted as env exports, a dotenv file or
+// JSON.
+func FormatSecret(data map[string][]byte, key string, output string) (string, error) {
+	keys := sortedKeys(data)
+	if key != "" {
+		if _, ok := data[key]; !ok {
+			return "", fmt.Errorf("secret has no key '%s', available keys: %s", key, strings.Join(keys, ", "))
+		}
+		keys = []string{key}
+	}
+
+	var sb strings.Builder
+	switch output {
+	case "":
+		if key != "" {
+			return string(data[key]), nil
+		}
+		for _, k := range keys {
+			sb.WriteString(k + "\n")
+		}
+	case SecretOutputEnv:
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "export %s=%s\n", envName(k), shellQuote(string(data[k])))
+		}
+	case SecretOutputDotenv:
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "%s=%s\n", envName(k), dotenvQuote(string(data[k])))
+		}
+	case SecretOutputJSON:
+		values := make(map[string]string, len(keys))
+		for _, k := range keys {
+			values[k] = string(data[k])
+		}
+		out, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		sb.Write(out)
+		sb.WriteString("\n")
+	default:
+		return "", fmt.Errorf("unknown output '%s', must be one of env, dotenv or json", output)
+	}
+	return sb.String(), nil
+}
+
+// CopyToClipboard pipes s to the clipboard command, which is run with sh.
+func CopyToClipboard(clipboardCommand string, s string) error {
+	if clipboardCommand == "" {
+		return fmt.Errorf("no clipboard command configured")
+	}
+
+	c := exec.Command("sh", "-c", clipboardCommand)
+	c.Stdin = strings.NewReader(s)
+	if output, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("clipboard command '%s' failed: %w: %s", clipboardCommand, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSecret(t *testing.T) {
+	data := map[string][]byte{
+		"root":    []byte("pa'ss\n"),
+		"tls.crt": []byte(`a"b$c`),
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		output   string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "List keys",
+			expected: "root\ntls.crt\n",
+		},
+		{
+			name:     "Raw value",
+			key:      "root",
+			expected: "pa'ss\n",
+		},
+		{
+			name: "Missing key",
+			key:  "missing",
+			err:  true,
+		},
+		{
+			name:     "Env",
+			output:   SecretOutputEnv,
+			expected: "export ROOT='pa'\\''ss\n'\nexport TLS_CRT='a\"b$c'\n",
+		},
+		{
+			name:     "Dotenv single key",
+			key:      "tls.crt",
+			output:   SecretOutputDotenv,
+			expected: "TLS_CRT=\"a\\\"b\\$c\"\n",
+		},
+		{
+			name:     "JSON",
+			output:   SecretOutputJSON,
+			expected: "{\n  \"root\": \"pa'ss\\n\",\n  \"tls.crt\": \"a\\\"b$c\"\n}\n",
+		},
+		{
+			name:   "Unknown output",
+			output: "yaml",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := FormatSecret(data, tt.key, tt.output)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, out)
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "TLS_CRT", envName("tls.crt"))
+	assert.Equal(t, "DB_PASSWORD", envName("db-password"))
+	assert.Equal(t, "_1KEY", envName("1key"))
+}
@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+)
+
+var shellCacheFile = []string{"k8s", "shells.json"}
+
+type podSpec struct {
+	Containers []struct {
+		Name  string `json:"name"`
+		Image string `json:"image"`
+	} `json:"containers"`
+}
+
+type shellTargetResource struct {
+	Spec struct {
+		podSpec
+		Template struct {
+			Spec podSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// ResolveShell finds the first of candidates available in the container
+// targeted by exec args by probing with `which`. Results are cached per image,
+// so subsequent execs into the same image skip the probe. If the probe itself
+// fails, ie. exec is forbidden, the first candidate is used.
+func (b *KubeBuilder) ResolveShell(context, namespace string, assumeClusterAdmin bool, args []string, candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", errors.New("no candidate shells configured")
+	}
+
+	parsedArgs := b.Substitute(slices.Clone(args), context, namespace)
+	target, container := execTarget(parsedArgs)
+
+	getArgs, _ := b.BuildKubectlArgs(context, namespace, false, false, nil)
+	image, err := containerImage(getArgs, target, container)
+	if err != nil {
+		// Not fatal, the probe below can still find a shell, it just can't be cached
+		image = ""
+	}
+
+	cache := make(map[string]string)
+	cachePath, err := state.CachePath(shellCacheFile...)
+	if err == nil && image != "" {
+		_ = state.ReadJSON(cachePath, &cache)
+		if shell, ok := cache[image]; ok {
+			return shell, nil
+		}
+	}
+
+	for _, shell := range candidates {
+		var stderr strings.Builder
+		c := exec.Command(Kubectl, b.shellProbeArgs(context, namespace, assumeClusterAdmin, target, container, shell)...)
+		c.Stdout = io.Discard
+		c.Stderr = &stderr
+		if err := c.Run(); err != nil {
+			if isMissingShell(stderr.String()) {
+				continue
+			}
+			return candidates[0], nil
+		}
+
+		if cachePath != "" && image != "" {
+			cache[image] = shell
+			if err := state.WriteJSON(cachePath, cache); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to cache shell for image %s: %v\n", image, err)
+			}
+		}
+		return shell, nil
+	}
+
+	return "", fmt.Errorf("none of the shells %s found in %s", strings.Join(candidates, ", "), target)
+}
+
+// shellProbeArgs returns the kubectl args probing for shell in the target
+// already resolved. The target is escaped so BuildKubectlArgs doesn't
+// substitute it again.
+func (b *KubeBuilder) shellProbeArgs(context, namespace string, assumeClusterAdmin bool, target, container, shell string) []string {
+	probe := []string{"exec", strings.ReplaceAll(target, "%", "%%")}
+	if container != "" {
+		probe = append(probe, "-c", strings.ReplaceAll(container, "%", "%%"))
+	}
+	probe = append(probe, "--", "which", shell)
+
+	args, _ := b.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, probe)
+	return args
+}
+
+// isMissingShell returns whether a failed probe ran `which` and it didn't find
+// the shell, rather than kubectl failing to exec at all.
+func isMissingShell(stderr string) bool {
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.TrimSpace(line) == "command terminated with exit code 1" {
+			return true
+		}
+	}
+	return false
+}
+
+// execTarget returns the resource and container targeted by exec args, ie.
+// `exec deploy foo -c bar` targets deploy/foo in container bar.
+func execTarget(args []string) (string, string) {
+	var target, container string
+	positional := make([]string, 0)
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			i = len(args)
+		case arg == "-c" || arg == "--container":
+			if i+1 < len(args) {
+				container = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--container="):
+			container = strings.TrimPrefix(arg, "--container=")
+		case strings.HasPrefix(arg, "-"):
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(positional) >= 2 && isModifiableResource(positional[0]) {
+		target = fmt.Sprintf("%s/%s", positional[0], positional[1])
+	} else if len(positional) >= 1 {
+		target = positional[0]
+	}
+
+	return target, container
+}
+
+func containerImage(baseArgs []string, target string, container string) (string, error) {
+	resource := target
+	if !strings.Contains(resource, "/") {
+		resource = fmt.Sprintf("pod/%s", resource)
+	}
+
+	c := exec.Command(Kubectl, append(slices.Clone(baseArgs), "get", resource, "-o", "json")...)
+	c.Stderr = io.Discard
+	output, err := c.Output()
+	if err != nil {
+		return "", err
+	}
+
+	var res shellTargetResource
+	if err := json.Unmarshal(output, &res); err != nil {
+		return "", err
+	}
+
+	containers := res.Spec.Containers
+	if len(containers) == 0 {
+		containers = res.Spec.Template.Spec.Containers
+	}
+	for _, c := range containers {
+		if container == "" || c.Name == container {
+			return c.Image, nil
+		}
+	}
+
+	return "", fmt.Errorf("container not found in %s", target)
+}
@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecTarget(t *testing.T) {
+	testCases := []struct {
+		name              string
+		args              []string
+		expectedTarget    string
+		expectedContainer string
+	}{
+		{
+			name:           "Pod",
+			args:           []string{"exec", "my-pod"},
+			expectedTarget: "my-pod",
+		},
+		{
+			name:           "Rewritable resource",
+			args:           []string{"exec", "deploy", "my-deploy"},
+			expectedTarget: "deploy/my-deploy",
+		},
+		{
+			name:              "Qualified resource with container",
+			args:              []string{"exec", "sts/my-sts", "-c", "my-container"},
+			expectedTarget:    "sts/my-sts",
+			expectedContainer: "my-container",
+		},
+		{
+			name:              "Container long flag",
+			args:              []string{"exec", "--container=my-container", "my-pod"},
+			expectedTarget:    "my-pod",
+			expectedContainer: "my-container",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, container := execTarget(tc.args)
+			assert.Equal(t, tc.expectedTarget, target)
+			assert.Equal(t, tc.expectedContainer, container)
+		})
+	}
+}
+
+func TestShellProbeArgs(t *testing.T) {
+	builder := NewKubeBuilder()
+
+	args := builder.shellProbeArgs("my-ctx", "my-ns", true, "my-pod", "my-container", "zsh")
+	assert.Contains(t, args, "--as=compute:cluster-admin")
+	assert.Equal(t, []string{"--", "which", "zsh"}, args[len(args)-3:])
+	assert.Contains(t, args, "my-container")
+
+	args = builder.shellProbeArgs("my-ctx", "my-ns", false, "my-pod", "", "bash")
+	assert.NotContains(t, args, "--as=compute:cluster-admin")
+	assert.NotContains(t, args, "-c")
+}
+
+func TestIsMissingShell(t *testing.T) {
+	assert.True(t, isMissingShell("command terminated with exit code 1\n"))
+	assert.False(t, isMissingShell("command terminated with exit code 127\n"))
+	assert.False(t, isMissingShell(`Error from server (Forbidden): pods "my-pod" is forbidden`))
+	assert.False(t, isMissingShell(""))
+}
@@ -0,0 +1,294 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// Restarts and events older than this are ignored
+	statusWindow = time.Hour
+	// Objects listed per warning event reason
+	maxEventObjects = 3
+)
+
+// statusKinds are fetched in a single kubectl call for the status summary.
+var statusKinds = []string{"pods", "persistentvolumeclaims", "events", "deployments", "statefulsets", "daemonsets"}
+
+// StatusOptions configures NamespaceStatus.
+type StatusOptions struct {
+	Context       string
+	Namespace     string
+	AllNamespaces bool
+	Debug         bool
+	DebugPrintf   func(context string, format string, args ...any)
+}
+
+type statusResource struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		Phase      string `json:"phase"`
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			RestartCount int    `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+			} `json:"state"`
+			LastState struct {
+				Terminated *struct {
+					Reason     string    `json:"reason"`
+					FinishedAt time.Time `json:"finishedAt"`
+				} `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+		ReadyReplicas          int `json:"readyReplicas"`
+		DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		NumberReady            int `json:"numberReady"`
+	} `json:"status"`
+
+	// Event fields
+	Type           string `json:"type"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Count          int    `json:"count"`
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"involvedObject"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+	EventTime     time.Time `json:"eventTime"`
+}
+
+// StatusIssue is an unhealthy resource in the status summary.
+type StatusIssue struct {
+	Namespace string
+	Name      string
+	Detail    string
+}
+
+// EventGroup aggregates recent Warning events with the same reason.
+type EventGroup struct {
+	Reason string
+	// Total occurrences since each event was first seen, which may be before
+	// the status window, as Kubernetes only records an event's lifetime count
+	Count int
+	// Up to maxEventObjects involved objects, ie. pod/foo
+	Objects []string
+	// Message of the most recent event
+	Message string
+	last    time.Time
+}
+
+// NamespaceStatus summarises the health of a namespace, or of all namespaces.
+type NamespaceStatus struct {
+	NotReadyPods    []StatusIssue
+	RestartedPods   []StatusIssue
+	PendingPVCs     []StatusIssue
+	UnderReplicated []StatusIssue
+	WarningEvents   []EventGroup
+}
+
+// Healthy returns whether no pods, PVCs or workloads have issues. Warning
+// events alone don't make a namespace unhealthy since they are often noise.
+func (s NamespaceStatus) Healthy() bool {
+	return len(s.NotReadyPods) == 0 && len(s.RestartedPods) == 0 && len(s.PendingPVCs) == 0 && len(s.UnderReplicated) == 0
+}
+
+// NamespaceStatus fetches pods, PVCs, events and workloads and summarises
+// their health.
+func (b *KubeBuilder) NamespaceStatus(opts StatusOptions) (NamespaceStatus, error) {
+	kubectlArgs, _ := b.BuildKubectlArgs(opts.Context, opts.Namespace, opts.AllNamespaces, false, []string{"get", strings.Join(statusKinds, ","), "-o", "json"})
+	if opts.Debug && opts.DebugPrintf != nil {
+		opts.DebugPrintf(opts.Context, "%s %s", Kubectl, strings.Join(kubectlArgs, " "))
+	}
+
+	var list k8sList[statusResource]
+	if err := DecodeKubectl(kubectlArgs, &list); err != nil {
+		return NamespaceStatus{}, err
+	}
+	return summarizeStatus(list.Items, time.Now()), nil
+}
+
+type k8sList[T any] struct {
+	Items []T `json:"items"`
+}
+
+func summarizeStatus(resources []statusResource, now time.Time) NamespaceStatus {
+	var status NamespaceStatus
+	events := make(map[string]*EventGroup)
+	for _, r := range resources {
+		issue := StatusIssue{Namespace: r.Metadata.Namespace, Name: r.Metadata.Name}
+		switch r.Kind {
+		case "Pod":
+			if detail, ok := podNotReady(r); ok {
+				issue.Detail = detail
+				status.NotReadyPods = append(status.NotReadyPods, issue)
+			}
+			if detail, ok := podRestarted(r, now); ok {
+				issue.Detail = detail
+				status.RestartedPods = append(status.RestartedPods, issue)
+			}
+		case "PersistentVolumeClaim":
+			if r.Status.Phase == "Pending" {
+				status.PendingPVCs = append(status.PendingPVCs, issue)
+			}
+		case "Deployment", "StatefulSet", "DaemonSet":
+			desired, ready := workloadReplicas(r)
+			if ready < desired {
+				issue.Name = fmt.Sprintf("%s/%s", strings.ToLower(r.Kind), r.Metadata.Name)
+				issue.Detail = fmt.Sprintf("%d/%d ready", ready, desired)
+				status.UnderReplicated = append(status.UnderReplicated, issue)
+			}
+		case "Event":
+			last := r.LastTimestamp
+			if last.IsZero() {
+				last = r.EventTime
+			}
+			if r.Type != "Warning" || now.Sub(last) > statusWindow {
+				continue
+			}
+
+			group, ok := events[r.Reason]
+			if !ok {
+				group = &EventGroup{Reason: r.Reason}
+				events[r.Reason] = group
+			}
+			count := r.Count
+			if count == 0 {
+				count = 1
+			}
+			group.Count += count
+			object := fmt.Sprintf("%s/%s", strings.ToLower(r.InvolvedObject.Kind), r.InvolvedObject.Name)
+			if len(group.Objects) < maxEventObjects && !slices.Contains(group.Objects, object) {
+				group.Objects = append(group.Objects, object)
+			}
+			if last.After(group.last) || group.Message == "" {
+				group.Message = r.Message
+				group.last = last
+			}
+		}
+	}
+
+	for _, group := range events {
+		status.WarningEvents = append(status.WarningEvents, *group)
+	}
+	sort.Slice(status.WarningEvents, func(i, j int) bool {
+		if status.WarningEvents[i].Count != status.WarningEvents[j].Count {
+			return status.WarningEvents[i].Count > status.WarningEvents[j].Count
+		}
+		return status.WarningEvents[i].Reason < status.WarningEvents[j].Reason
+	})
+	return status
+}
+
+// podNotReady returns why a pod that hasn't completed isn't Ready.
+func podNotReady(r statusResource) (string, bool) {
+	if r.Status.Phase == "Succeeded" {
+		return "", false
+	}
+	for _, condition := range r.Status.Conditions {
+		if condition.Type == "Ready" && condition.Status == "True" {
+			return "", false
+		}
+	}
+
+	for _, cs := range r.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			return cs.State.Waiting.Reason, true
+		}
+	}
+	if r.Status.Phase == "" {
+		return "Unknown", true
+	}
+	return r.Status.Phase, true
+}
+
+// podRestarted returns the containers of a pod which restarted within the
+// status window.
+func podRestarted(r statusResource, now time.Time) (string, bool) {
+	restarted := make([]string, 0)
+	for _, cs := range r.Status.ContainerStatuses {
+		terminated := cs.LastState.Terminated
+		if cs.RestartCount == 0 || terminated == nil || now.Sub(terminated.FinishedAt) > statusWindow {
+			continue
+		}
+		restarted = append(restarted, fmt.Sprintf("%s restarted %d times, last %s", cs.Name, cs.RestartCount, terminated.Reason))
+	}
+	if len(restarted) == 0 {
+		return "", false
+	}
+	return strings.Join(restarted, ", "), true
+}
+
+func workloadReplicas(r statusResource) (int, int) {
+	if r.Kind == "DaemonSet" {
+		return r.Status.DesiredNumberScheduled, r.Status.NumberReady
+	}
+
+	desired := 1
+	if r.Spec.Replicas != nil {
+		desired = *r.Spec.Replicas
+	}
+	return desired, r.Status.ReadyReplicas
+}
+
+// Print writes the summary, prefixing names with their namespace if
+// withNamespace is set.
+func (s NamespaceStatus) Print(w io.Writer, withNamespace bool) {
+	header := color.New(color.FgRed, color.Bold)
+	name := func(issue StatusIssue) string {
+		if withNamespace {
+			return fmt.Sprintf("%s/%s", issue.Namespace, issue.Name)
+		}
+		return issue.Name
+	}
+	section := func(title string, issues []StatusIssue) {
+		if len(issues) == 0 {
+			return
+		}
+		fmt.Fprintln(w, header.Sprintf("%s (%d)", title, len(issues)))
+		for _, issue := range issues {
+			if issue.Detail != "" {
+				fmt.Fprintf(w, "  %s: %s\n", name(issue), issue.Detail)
+			} else {
+				fmt.Fprintf(w, "  %s\n", name(issue))
+			}
+		}
+	}
+
+	section("Pods not ready", s.NotReadyPods)
+	section("Pods restarted in the last hour", s.RestartedPods)
+	section("Pending PVCs", s.PendingPVCs)
+	section("Workloads below desired replicas", s.UnderReplicated)
+
+	if len(s.WarningEvents) > 0 {
+		fmt.Fprintln(w, color.New(color.FgYellow, color.Bold).Sprintf("Warning events seen in the last hour (%d reasons)", len(s.WarningEvents)))
+		for _, group := range s.WarningEvents {
+			fmt.Fprintf(w, "  %s x%d total: %s\n", group.Reason, group.Count, strings.Join(group.Objects, ", "))
+			fmt.Fprintf(w, "    %s\n", strings.TrimSpace(group.Message))
+		}
+	}
+
+	if s.Healthy() {
+		fmt.Fprintln(w, color.New(color.FgGreen, color.Bold).Sprint("Healthy"))
+	}
+}
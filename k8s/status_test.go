@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const statusFixture = `{
+  "kind": "List",
+  "items": [
+    {
+      "kind": "Pod",
+      "metadata": {"name": "web-0", "namespace": "app"},
+      "status": {
+        "phase": "Running",
+        "conditions": [{"type": "Ready", "status": "True"}],
+        "containerStatuses": [{"name": "web", "restartCount": 0}]
+      }
+    },
+    {
+      "kind": "Pod",
+      "metadata": {"name": "web-1", "namespace": "app"},
+      "status": {
+        "phase": "Running",
+        "conditions": [{"type": "Ready", "status": "False"}],
+        "containerStatuses": [{
+          "name": "web",
+          "restartCount": 4,
+          "state": {"waiting": {"reason": "CrashLoopBackOff"}},
+          "lastState": {"terminated": {"reason": "Error", "finishedAt": "2024-05-01T11:50:00Z"}}
+        }]
+      }
+    },
+    {
+      "kind": "Pod",
+      "metadata": {"name": "web-2", "namespace": "app"},
+      "status": {
+        "phase": "Running",
+        "conditions": [{"type": "Ready", "status": "True"}],
+        "containerStatuses": [{
+          "name": "web",
+          "restartCount": 1,
+          "lastState": {"terminated": {"reason": "OOMKilled", "finishedAt": "2024-05-01T08:00:00Z"}}
+        }]
+      }
+    },
+    {
+      "kind": "Pod",
+      "metadata": {"name": "migrate", "namespace": "app"},
+      "status": {"phase": "Succeeded"}
+    },
+    {
+      "kind": "PersistentVolumeClaim",
+      "metadata": {"name": "data-web-0", "namespace": "app"},
+      "status": {"phase": "Bound"}
+    },
+    {
+      "kind": "PersistentVolumeClaim",
+      "metadata": {"name": "data-web-3", "namespace": "app"},
+      "status": {"phase": "Pending"}
+    },
+    {
+      "kind": "StatefulSet",
+      "metadata": {"name": "web", "namespace": "app"},
+      "spec": {"replicas": 3},
+      "status": {"readyReplicas": 2}
+    },
+    {
+      "kind": "Deployment",
+      "metadata": {"name": "api", "namespace": "app"},
+      "spec": {"replicas": 2},
+      "status": {"readyReplicas": 2}
+    },
+    {
+      "kind": "DaemonSet",
+      "metadata": {"name": "agent", "namespace": "app"},
+      "status": {"desiredNumberScheduled": 5, "numberReady": 5}
+    },
+    {
+      "kind": "Event",
+      "metadata": {"name": "e1", "namespace": "app"},
+      "type": "Warning",
+      "reason": "BackOff",
+      "message": "Back-off restarting failed container",
+      "count": 10,
+      "involvedObject": {"kind": "Pod", "name": "web-1"},
+      "lastTimestamp": "2024-05-01T11:55:00Z"
+    },
+    {
+      "kind": "Event",
+      "metadata": {"name": "e2", "namespace": "app"},
+      "type": "Warning",
+      "reason": "FailedScheduling",
+      "message": "0/3 nodes are available",
+      "involvedObject": {"kind": "Pod", "name": "web-3"},
+      "eventTime": "2024-05-01T11:58:00.000000Z"
+    },
+    {
+      "kind": "Event",
+      "metadata": {"name": "e3", "namespace": "app"},
+      "type": "Warning",
+      "reason": "BackOff",
+      "message": "old",
+      "count": 3,
+      "involvedObject": {"kind": "Pod", "name": "web-2"},
+      "lastTimestamp": "2024-05-01T09:00:00Z"
+    },
+    {
+      "kind": "Event",
+      "metadata": {"name": "e4", "namespace": "app"},
+      "type": "Normal",
+      "reason": "Pulled",
+      "involvedObject": {"kind": "Pod", "name": "web-1"},
+      "lastTimestamp": "2024-05-01T11:59:00Z"
+    }
+  ]
+}`
+
+func TestSummarizeStatus(t *testing.T) {
+	var list k8sList[statusResource]
+	assert.NoError(t, json.Unmarshal([]byte(statusFixture), &list))
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	status := summarizeStatus(list.Items, now)
+
+	assert.Equal(t, []StatusIssue{
+		{Namespace: "app", Name: "web-1", Detail: "CrashLoopBackOff"},
+	}, status.NotReadyPods)
+	assert.Equal(t, []StatusIssue{
+		{Namespace: "app", Name: "web-1", Detail: "web restarted 4 times, last Error"},
+	}, status.RestartedPods)
+	assert.Equal(t, []StatusIssue{
+		{Namespace: "app", Name: "data-web-3"},
+	}, status.PendingPVCs)
+	assert.Equal(t, []StatusIssue{
+		{Namespace: "app", Name: "statefulset/web", Detail: "2/3 ready"},
+	}, status.UnderReplicated)
+
+	assert.Len(t, status.WarningEvents, 2)
+	assert.Equal(t, "BackOff", status.WarningEvents[0].Reason)
+	assert.Equal(t, 10, status.WarningEvents[0].Count)
+	assert.Equal(t, []string{"pod/web-1"}, status.WarningEvents[0].Objects)
+	assert.Equal(t, "FailedScheduling", status.WarningEvents[1].Reason)
+	assert.Equal(t, 1, status.WarningEvents[1].Count)
+
+	assert.False(t, status.Healthy())
+}
+
+func TestSummarizeStatusHealthy(t *testing.T) {
+	replicas := 1
+	resource := statusResource{Kind: "Deployment"}
+	resource.Spec.Replicas = &replicas
+	resource.Status.ReadyReplicas = 1
+
+	status := summarizeStatus([]statusResource{resource}, time.Now())
+	assert.True(t, status.Healthy())
+}
+
+func TestNamespaceStatusPrintEvents(t *testing.T) {
+	status := NamespaceStatus{
+		WarningEvents: []EventGroup{{Reason: "BackOff", Count: 10, Objects: []string{"pod/web-1"}, Message: "Back-off restarting"}},
+	}
+
+	var out bytes.Buffer
+	status.Print(&out, false)
+	assert.Contains(t, out.String(), "Warning events seen in the last hour (1 reasons)")
+	assert.Contains(t, out.String(), "  BackOff x10 total: pod/web-1\n")
+}
@@ -0,0 +1,245 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+)
+
+const (
+	StickyScopeTmux  = "tmux"
+	StickyScopeShell = "shell"
+	StickyScopeDir   = "dir"
+)
+
+var stickyStateFile = []string{"k8s", "sticky.json"}
+
+// StickyTarget is a default context and namespace recorded by `mdcli k8s use`
+// for a tmux pane, shell or directory.
+type StickyTarget struct {
+	Scope     string    `json:"scope"`
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// stickyScopeKey returns the key identifying the current tmux pane, shell or
+// directory, or "" if the scope isn't available.
+func stickyScopeKey(scope string) (string, error) {
+	switch scope {
+	case StickyScopeTmux:
+		pane := os.Getenv("TMUX_PANE")
+		socket, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+		if pane == "" || socket == "" {
+			return "", nil
+		}
+		return fmt.Sprintf("tmux:%s:%s", socket, pane), nil
+	case StickyScopeShell:
+		return fmt.Sprintf("shell:%d", os.Getppid()), nil
+	case StickyScopeDir:
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		return "dir:" + wd, nil
+	default:
+		return "", fmt.Errorf("unknown sticky scope '%s', must be one of tmux, shell or dir", scope)
+	}
+}
+
+// DefaultStickyScope is the most specific scope available, ie. the tmux pane
+// when running in tmux and the shell otherwise.
+func DefaultStickyScope() string {
+	if key, _ := stickyScopeKey(StickyScopeTmux); key != "" {
+		return StickyScopeTmux
+	}
+	return StickyScopeShell
+}
+
+func loadStickyTargets() (map[string]StickyTarget, error) {
+	path, err := state.Path(stickyStateFile...)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]StickyTarget)
+	if err := state.ReadJSON(path, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func updateStickyTargets(fn func(map[string]StickyTarget)) error {
+	path, err := state.Path(stickyStateFile...)
+	if err != nil {
+		return err
+	}
+
+	return state.WithLock(path, func() error {
+		targets := make(map[string]StickyTarget)
+		if err := state.ReadJSON(path, &targets); err != nil {
+			return err
+		}
+
+		// Drop targets for shells that have exited
+		for key := range targets {
+			if pid, ok := strings.CutPrefix(key, "shell:"); ok {
+				if pid, err := strconv.Atoi(pid); err == nil && syscall.Kill(pid, 0) != nil {
+					delete(targets, key)
+				}
+			}
+		}
+
+		fn(targets)
+		return state.WriteJSON(path, targets)
+	})
+}
+
+// SetStickyTarget records context and namespace as the default for scope.
+func SetStickyTarget(scope, context, namespace string) (StickyTarget, error) {
+	key, err := stickyScopeKey(scope)
+	if err != nil {
+		return StickyTarget{}, err
+	}
+	if key == "" {
+		return StickyTarget{}, fmt.Errorf("%s scope is not available", scope)
+	}
+
+	target := StickyTarget{
+		Scope:     key,
+		Context:   context,
+		Namespace: namespace,
+		UpdatedAt: time.Now(),
+	}
+	err = updateStickyTargets(func(targets map[string]StickyTarget) {
+		targets[key] = target
+	})
+	return target, err
+}
+
+// ClearStickyTarget removes the default for scope.
+func ClearStickyTarget(scope string) error {
+	key, err := stickyScopeKey(scope)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("%s scope is not available", scope)
+	}
+
+	return updateStickyTargets(func(targets map[string]StickyTarget) {
+		delete(targets, key)
+	})
+}
+
+// LookupSticky returns the sticky target for the most specific matching scope:
+// the tmux pane, then the shell, then the current directory or its parents.
+func LookupSticky() (StickyTarget, bool) {
+	targets, err := loadStickyTargets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load sticky context: %v\n", err)
+		return StickyTarget{}, false
+	}
+	if len(targets) == 0 {
+		return StickyTarget{}, false
+	}
+
+	for _, scope := range []string{StickyScopeTmux, StickyScopeShell} {
+		key, err := stickyScopeKey(scope)
+		if err != nil || key == "" {
+			continue
+		}
+		if target, ok := targets[key]; ok {
+			return target, true
+		}
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return StickyTarget{}, false
+	}
+	for {
+		if target, ok := targets["dir:"+dir]; ok {
+			return target, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return StickyTarget{}, false
+}
+
+// StickyContext returns the sticky context, if any, and a note describing its
+// use. resolve, if set, maps the stored context to a full context name, and the
+// result must match pattern if one is given.
+func StickyContext(pattern string, resolve func(context string) string) (string, string, bool) {
+	target, ok := LookupSticky()
+	if !ok || target.Context == "" {
+		return "", "", false
+	}
+
+	context := target.Context
+	if resolve != nil {
+		context = resolve(context)
+	}
+	if !matchesPattern(context, pattern) {
+		return "", "", false
+	}
+
+	return context, fmt.Sprintf("context %s from sticky state (%s)", context, target.Scope), true
+}
+
+// StickyNamespace returns the sticky namespace, if any, and a note describing
+// its use. It only applies if the sticky context, mapped through resolve if
+// set, is context and the namespace matches pattern if one is given.
+func StickyNamespace(context string, pattern string, resolve func(context string) string) (string, string, bool) {
+	target, ok := LookupSticky()
+	if !ok || target.Namespace == "" || !matchesPattern(target.Namespace, pattern) {
+		return "", "", false
+	}
+
+	stickyContext := target.Context
+	if resolve != nil {
+		stickyContext = resolve(stickyContext)
+	}
+	if stickyContext != context {
+		return "", "", false
+	}
+
+	return target.Namespace, fmt.Sprintf("namespace %s from sticky state (%s)", target.Namespace, target.Scope), true
+}
+
+func matchesPattern(s string, pattern string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := regexp.MatchString(pattern, s)
+	return err == nil && ok
+}
+
+// StickyUsage joins the notes returned by ParseContext and ParseNamespace,
+// which are "" unless the sticky state was used.
+func StickyUsage(notes ...string) string {
+	return strings.Join(slices.DeleteFunc(slices.Clone(notes), func(note string) bool {
+		return note == ""
+	}), ", ")
+}
+
+func (t StickyTarget) String() string {
+	if t.Namespace == "" {
+		return t.Context
+	}
+	return fmt.Sprintf("%s/%s", t.Context, t.Namespace)
+}
@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var stickyScopeFlag = &cli.StringFlag{
+	Name:  "scope",
+	Value: "",
+	Usage: "`SCOPE` of the default, one of tmux, shell or dir. Defaults to the tmux pane if in tmux, else the shell",
+}
+
+func useCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "use",
+		Usage:     "Set the default context and namespace for the current tmux pane, shell or directory",
+		ArgsUsage: "<context> [namespace]",
+		Flags: []cli.Flag{
+			stickyScopeFlag,
+			&cli.BoolFlag{
+				Name:  "clear",
+				Value: false,
+				Usage: "Clear the default instead of setting it",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			scope := cCtx.String("scope")
+			if scope == "" {
+				scope = DefaultStickyScope()
+			}
+
+			if cCtx.Bool("clear") {
+				if err := ClearStickyTarget(scope); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				fmt.Printf("Cleared default for %s\n", scope)
+				return nil
+			}
+
+			if cCtx.NArg() < 1 || cCtx.NArg() > 2 {
+				return cli.Exit("a context and optional namespace must be provided", 1)
+			}
+
+			target, err := SetStickyTarget(scope, cCtx.Args().Get(0), cCtx.Args().Get(1))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Printf("Using %s for %s\n", target, target.Scope)
+			return nil
+		},
+	}
+}
+
+func currentCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "current",
+		Usage: "Show the default context and namespace set by use",
+		Action: func(cCtx *cli.Context) error {
+			target, ok := LookupSticky()
+			if !ok {
+				return cli.Exit("no default context set", 1)
+			}
+
+			fmt.Printf("%s (%s, set %s ago)\n", target, target.Scope, time.Since(target.UpdatedAt).Round(time.Second))
+			return nil
+		},
+	}
+}
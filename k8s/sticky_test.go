@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func TestLookupSticky(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+
+	dir := t.TempDir()
+	subdir := filepath.Join(dir, "sub")
+	assert.NoError(t, os.Mkdir(subdir, 0755))
+	chdir(t, dir)
+
+	_, ok := LookupSticky()
+	assert.False(t, ok)
+
+	_, err := SetStickyTarget(StickyScopeDir, "dir-context", "dir-namespace")
+	assert.NoError(t, err)
+
+	// Directory defaults apply to subdirectories
+	chdir(t, subdir)
+	target, ok := LookupSticky()
+	assert.True(t, ok)
+	assert.Equal(t, "dir-context", target.Context)
+
+	// The shell takes precedence over the directory
+	_, err = SetStickyTarget(StickyScopeShell, "shell-context", "")
+	assert.NoError(t, err)
+	target, ok = LookupSticky()
+	assert.True(t, ok)
+	assert.Equal(t, "shell-context", target.Context)
+
+	assert.NoError(t, ClearStickyTarget(StickyScopeShell))
+	target, ok = LookupSticky()
+	assert.True(t, ok)
+	assert.Equal(t, "dir-context", target.Context)
+
+	_, err = SetStickyTarget(StickyScopeTmux, "tmux-context", "")
+	assert.Error(t, err)
+}
+
+func TestStickyNamespace(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+
+	_, err := SetStickyTarget(StickyScopeShell, "my-context", "my-namespace")
+	assert.NoError(t, err)
+
+	namespace, note, ok := StickyNamespace("my-context", "", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "my-namespace", namespace)
+	assert.Equal(t, "namespace my-namespace from sticky state (shell:"+strconv.Itoa(os.Getppid())+")", note)
+
+	// The namespace only applies to the sticky context
+	_, _, ok = StickyNamespace("other-context", "", nil)
+	assert.False(t, ok)
+
+	_, _, ok = StickyNamespace("my-context", "^tidb-", nil)
+	assert.False(t, ok)
+
+	namespace, _, ok = StickyNamespace("full-context", "", func(context string) string {
+		return map[string]string{"my-context": "full-context"}[context]
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "my-namespace", namespace)
+
+	context, _, ok := StickyContext("^my-", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "my-context", context)
+
+	_, _, ok = StickyContext("^m-tidb-", nil)
+	assert.False(t, ok)
+}
+
+func TestParseStickyUsage(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+
+	_, err := SetStickyTarget(StickyScopeShell, "my-context", "my-namespace")
+	assert.NoError(t, err)
+	scope := "shell:" + strconv.Itoa(os.Getppid())
+
+	context, contextNote, err := ParseContext("", false, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-context", context)
+	namespace, _, namespaceNote, err := ParseNamespace("", false, false, context, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-namespace", namespace)
+	assert.Equal(t, "context my-context from sticky state ("+scope+"), namespace my-namespace from sticky state ("+scope+")", StickyUsage(contextNote, namespaceNote))
+
+	// Flags aren't noted, and each call's notes are independent
+	_, contextNote, err = ParseContext("other-context", false, "", true)
+	assert.NoError(t, err)
+	_, _, namespaceNote, err = ParseNamespace("other-namespace", false, false, "other-context", "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "", StickyUsage(contextNote, namespaceNote))
+}
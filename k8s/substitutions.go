@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/michaelmdeng/mdcli/internal/config"
+)
+
+type substitutionData struct {
+	Context   string
+	Namespace string
+}
+
+// NewConfigSubstitution creates a Substitution from a user-defined config
+// entry.
+func NewConfigSubstitution(cfg config.SubstitutionConfig) (Substitution, error) {
+	if len(cfg.Aliases) == 0 {
+		return Substitution{}, errors.New("substitution must define at least one alias")
+	}
+
+	set := 0
+	for _, v := range []string{cfg.Value, cfg.Template, cfg.Command} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return Substitution{}, fmt.Errorf("substitution %s must define exactly one of value, template or command", cfg.Aliases[0])
+	}
+
+	if cfg.Value != "" {
+		value := cfg.Value
+		return Substitution{
+			Aliases: cfg.Aliases,
+			Generate: func(context, namespace string) (string, error) {
+				return value, nil
+			},
+		}, nil
+	}
+
+	text := cfg.Template
+	if cfg.Command != "" {
+		text = cfg.Command
+	}
+	tmpl, err := template.New(cfg.Aliases[0]).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return Substitution{}, fmt.Errorf("failed to parse substitution %s: %w", cfg.Aliases[0], err)
+	}
+
+	render := func(context, namespace string) (string, error) {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, substitutionData{Context: context, Namespace: namespace}); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+
+	if cfg.Template != "" {
+		return Substitution{
+			Aliases:  cfg.Aliases,
+			Generate: render,
+		}, nil
+	}
+
+	return Substitution{
+		Aliases: cfg.Aliases,
+		Generate: func(context, namespace string) (string, error) {
+			command, err := render(context, namespace)
+			if err != nil {
+				return "", err
+			}
+
+			c := exec.Command("sh", "-c", command)
+			c.Stderr = os.Stderr
+			output, err := c.Output()
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(output)), nil
+		},
+	}, nil
+}
+
+// AddConfigSubstitutions appends the user-defined substitutions from config
+// after the builder's built-in ones.
+func (b *KubeBuilder) AddConfigSubstitutions(cfgs []config.SubstitutionConfig) error {
+	for _, cfg := range cfgs {
+		sub, err := NewConfigSubstitution(cfg)
+		if err != nil {
+			return err
+		}
+		b.AddSubstitutions(sub)
+	}
+	return nil
+}
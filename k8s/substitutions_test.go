@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/michaelmdeng/mdcli/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfigSubstitution(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         config.SubstitutionConfig
+		expected    string
+		expectError bool
+	}{
+		{
+			name: "Static value",
+			cfg: config.SubstitutionConfig{
+				Aliases: []string{"team"},
+				Value:   "storage",
+			},
+			expected: "storage",
+		},
+		{
+			name: "Template",
+			cfg: config.SubstitutionConfig{
+				Aliases:  []string{"sa"},
+				Template: "{{ .Namespace }}-sa@{{ .Context }}",
+			},
+			expected: "my-namespace-sa@my-context",
+		},
+		{
+			name: "Command",
+			cfg: config.SubstitutionConfig{
+				Aliases: []string{"upper"},
+				Command: "echo {{ .Namespace }} | tr a-z A-Z",
+			},
+			expected: "MY-NAMESPACE",
+		},
+		{
+			name: "No aliases",
+			cfg: config.SubstitutionConfig{
+				Value: "storage",
+			},
+			expectError: true,
+		},
+		{
+			name: "No value",
+			cfg: config.SubstitutionConfig{
+				Aliases: []string{"team"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Multiple values",
+			cfg: config.SubstitutionConfig{
+				Aliases:  []string{"team"},
+				Value:    "storage",
+				Template: "{{ .Namespace }}",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid template",
+			cfg: config.SubstitutionConfig{
+				Aliases:  []string{"team"},
+				Template: "{{ .Namespace",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, err := NewConfigSubstitution(tc.cfg)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			result, err := sub.Generate("my-context", "my-namespace")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestAddConfigSubstitutions(t *testing.T) {
+	builder := NewKubeBuilder()
+	err := builder.AddConfigSubstitutions([]config.SubstitutionConfig{
+		{
+			Aliases: []string{"team", "n"},
+			Value:   "storage",
+		},
+	})
+	assert.NoError(t, err)
+
+	// Built-in aliases take precedence over config-defined ones
+	result := builder.Substitute([]string{"%team", "%n"}, "my-context", "my-namespace")
+	assert.Equal(t, []string{"storage", "my-namespace"}, result)
+}
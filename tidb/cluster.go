@@ -537,54 +537,90 @@ func inferNamespace(context string, namespace string) (string, bool) {
 	return namespace, false
 }
 
-func ParseContext(context string, interactive bool, pattern string, strict bool) (string, error) {
+// resolveFanoutTarget resolves context and namespace aliases for commands run
+// across multiple targets. Environment-specific context aliases, ie. `a`, are
+// inferred from the namespace with any trailing glob removed.
+func resolveFanoutTarget(context string, namespace string) (string, string) {
+	if inferred := inferContextFromNamespace(context, strings.TrimRight(namespace, "*?")); inferred != "" {
+		context = inferred
+	}
+
+	namespace, _ = inferNamespace(context, namespace)
+	return context, namespace
+}
+
+// resolveStickyContext maps a sticky context, which may be an alias set via
+// `mdcli k8s use`, to the full context name.
+func resolveStickyContext(context string) string {
+	resolved, _ := inferContext(context)
+	return resolved
+}
+
+// ParseContext resolves and infers the context like mdk8s.ParseContext.
+func ParseContext(context string, interactive bool, pattern string, strict bool) (string, string, error) {
 	if context != "" {
 		context, _ = inferContext(context)
-		return context, nil
+		return context, "", nil
+	}
+
+	if sticky, note, ok := mdk8s.StickyContext(pattern, resolveStickyContext); ok {
+		return sticky, note, nil
 	}
 
 	if interactive && context == "" {
 		var err error
 		context, err = mdk8s.GetContextInteractive(pattern)
 		if strict && err != nil {
-			return "", err
+			return "", "", err
 		} else if err != nil {
 			context = ""
 		}
 	}
 
 	if strict && context == "" {
-		return "", errors.New("context must be specified in strict mode")
+		return "", "", errors.New("context must be specified in strict mode")
 	}
 
-	return context, nil
+	return context, "", nil
 }
 
-func ParseNamespace(namespace string, allNamespaces bool, interactive bool, context string, pattern string, strict bool) (string, bool, error) {
+// ParseNamespace resolves and infers the namespace like mdk8s.ParseNamespace.
+// Namespaces inferred from an alias or chosen with the picker are recorded as
+// recent.
+func ParseNamespace(namespace string, allNamespaces bool, interactive bool, context string, pattern string, strict bool) (string, bool, string, error) {
 	if allNamespaces || namespace == "*" {
-		return "", true, nil
+		return "", true, "", nil
 	}
 
 	if namespace != "" {
-		namespace, _ = inferNamespace(context, namespace)
-		return namespace, false, nil
+		namespace, inferred := inferNamespace(context, namespace)
+		if inferred {
+			mdk8s.RecordRecentTarget(context, namespace)
+		}
+		return namespace, false, "", nil
+	}
+
+	if sticky, note, ok := mdk8s.StickyNamespace(context, "", resolveStickyContext); ok {
+		sticky, _ = inferNamespace(context, sticky)
+		return sticky, false, note, nil
 	}
 
 	if interactive && !allNamespaces && namespace == "" {
 		var err error
 		namespace, err = mdk8s.GetNamespaceInteractive(context, pattern)
 		if strict && err != nil {
-			return "", false, err
+			return "", false, "", err
 		} else if err != nil {
 			namespace = ""
 		}
+		mdk8s.RecordRecentTarget(context, namespace)
 	}
 
 	if strict && namespace == "" {
-		return "", false, errors.New("namespace must be specified in strict mode")
+		return "", false, "", errors.New("namespace must be specified in strict mode")
 	}
 
-	return namespace, false, nil
+	return namespace, false, "", nil
 }
 
 var (
@@ -2,11 +2,9 @@ package tidb
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"os/exec"
 	"strings"
-	"time"
 
 	"github.com/bitfield/script"
 	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
@@ -32,6 +30,10 @@ func BaseCommand() *cli.Command {
 		Subcommands: []*cli.Command{
 			tidbSecretCommand(),
 			tidbKubectlCommand(),
+			tidbShellCommand(),
+			tidbLogsCommand(),
+			tidbHealthCommand(),
+			tidbStatusCommand(),
 			tidbK9sCommand(),
 			tidbMysqlCommand(),
 			tidbDmctlCommand(),
@@ -39,6 +41,8 @@ func BaseCommand() *cli.Command {
 			ticdcCommand(),
 			BasePdCommand(),
 			BaseTikvCommand(),
+			tidbKeyCommand(),
+			tidbRegionCommand(),
 		},
 	}
 }
@@ -59,12 +63,17 @@ func tidbSecretCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, _, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			context, _, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, _, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -85,9 +94,9 @@ func tidbKubectlCommand() *cli.Command {
 		Name:    "kubectl",
 		Aliases: []string{"kc", "kctl", "tkc", "tkctl"},
 		Usage:   "kubectl wrapper for TiDB",
-		Flags:   append(mdk8s.BaseK8sFlags, mdk8s.BaseKctlFlags...),
+		Flags:   append(append(append(mdk8s.BaseK8sFlags, mdk8s.BaseKctlFlags...), mdk8s.FanoutFlags...), mdk8s.JqFlags...),
 		Action: func(cCtx *cli.Context) error {
-			cfg := config.NewConfig()
+			cfg := config.FromMetadata(cCtx.App.Metadata)
 
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
@@ -97,27 +106,88 @@ func tidbKubectlCommand() *cli.Command {
 			allNamespaces := cCtx.Bool("all-namespaces")
 			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
 			confirmed := cCtx.Bool("yes")
+			shell := cCtx.String("shell")
+			fanoutContexts := cCtx.StringSlice("contexts")
+			fanoutNamespaces := cCtx.StringSlice("namespaces")
 
-			context = inferContextFromNamespace(context, namespace)
-
-			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			jq, err := mdk8s.ParseJqFlags(cCtx)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
+			kubectlArgs := cCtx.Args().Slice()
+			if jq != nil {
+				kubectlArgs = mdk8s.WithJSONOutput(kubectlArgs)
+			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
-			if err != nil {
-				return cli.Exit(err.Error(), 1)
+			var contextNote, namespaceNote string
+			if len(fanoutContexts) == 0 {
+				context = inferContextFromNamespace(context, namespace)
+
+				context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+
+				context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			}
+
+			if len(fanoutNamespaces) == 0 {
+				namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			}
+
+			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
+
+			if len(fanoutContexts) > 0 || len(fanoutNamespaces) > 0 {
+				if len(fanoutContexts) == 0 {
+					fanoutContexts = []string{context}
+				}
+				if len(fanoutNamespaces) == 0 {
+					fanoutNamespaces = []string{namespace}
+				}
+
+				targets, err := mdk8s.FanoutTargets(fanoutContexts, fanoutNamespaces, allNamespaces, resolveFanoutTarget)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+
+				return builder.RunKubectlFanout(targets, kubectlArgs, mdk8s.FanoutOptions{
+					Parallelism: cCtx.Int("parallelism"),
+					Jq:          jq,
+					Confirmed:   confirmed,
+					Debug:       debug,
+					AssumeClusterAdmin: func(context string) bool {
+						return assumeClusterAdmin || (isTestTidbContext(context) && cfg.EnableClusterAdminForTest)
+					},
+					DebugPrintf: colorDebugPrintfln,
+				})
 			}
 
 			if isTestTidbContext(context) {
 				assumeClusterAdmin = assumeClusterAdmin || cfg.EnableClusterAdminForTest
 			}
-			builder := NewTidbKubeBuilder()
-			args, confirm := builder.BuildKubectlArgs(context, namespace, allNamespaces, assumeClusterAdmin, cCtx.Args().Slice())
+			builder.Shell = shell
+			if builder.Shell == "" && builder.IsInteractiveExec(cCtx.Args().Slice()) {
+				builder.Shell, err = builder.ResolveShell(context, namespace, assumeClusterAdmin, cCtx.Args().Slice(), cfg.K8s.Shells)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Failed to resolve shell: %v", err), 1)
+				}
+			}
+
+			args, confirm := builder.BuildKubectlArgs(context, namespace, allNamespaces, assumeClusterAdmin, kubectlArgs)
 
 			needsConfirm := confirm && !confirmed
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
 			if debug || needsConfirm {
 				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
 			}
@@ -130,11 +200,187 @@ func tidbKubectlCommand() *cli.Command {
 			}
 
 			// Use the helper function for external command errors
+			if jq != nil {
+				return mdexec.ExitError(mdk8s.RunKubectlJq(args, jq))
+			}
 			return mdexec.ExitError(mdexec.RunCommand(mdk8s.Kubectl, args...))
 		},
 	}
 }
 
+func tidbShellCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "shell",
+		Usage: "Interactive kubectl shell for TiDB",
+		Flags: append(mdk8s.BaseK8sFlags, mdk8s.BaseKctlFlags...),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			debug := cCtx.Bool("debug")
+			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+			return mdexec.ExitError(builder.RunRepl(mdk8s.ReplOptions{
+				Context:   context,
+				Namespace: namespace,
+				Confirmed: cCtx.Bool("yes"),
+				Debug:     debug,
+				Shell:     cCtx.String("shell"),
+				Shells:    cfg.K8s.Shells,
+				AssumeClusterAdmin: func(context string) bool {
+					return assumeClusterAdmin || (isTestTidbContext(context) && cfg.EnableClusterAdminForTest)
+				},
+				Resolve:     resolveFanoutTarget,
+				DebugPrintf: colorDebugPrintfln,
+			}))
+		},
+	}
+}
+
+func tidbLogsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "logs",
+		Aliases:   []string{"log", "tlogs"},
+		Usage:     "Tail logs from multiple TiDB pods and containers",
+		ArgsUsage: "[pod-regex]",
+		Flags:     append(append(mdk8s.BaseK8sFlags, mdk8s.BaseKctlFlags...), mdk8s.LogsFlags...),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+
+			opts, err := mdk8s.NewLogsOptions(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if isTestTidbContext(context) {
+				opts.AssumeClusterAdmin = opts.AssumeClusterAdmin || cfg.EnableClusterAdminForTest
+			}
+			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
+
+			opts.Context = context
+			opts.Namespace = namespace
+			opts.DebugPrintf = colorDebugPrintfln
+			if opts.Debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+			return mdexec.ExitError(builder.TailLogs(opts))
+		},
+	}
+}
+
+func tidbHealthCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "health",
+		Usage: "Summarise the health of a TiDB cluster namespace. Exits non-zero if unhealthy",
+		Flags: mdk8s.BaseK8sFlags,
+		Action: func(cCtx *cli.Context) error {
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			allNamespaces := cCtx.Bool("all-namespaces")
+			debug := cCtx.Bool("debug")
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+
+			builder := NewTidbKubeBuilder()
+			status, err := builder.NamespaceStatus(mdk8s.StatusOptions{
+				Context:       context,
+				Namespace:     namespace,
+				AllNamespaces: allNamespaces,
+				Debug:         debug,
+				DebugPrintf:   colorDebugPrintfln,
+			})
+			if err != nil {
+				return mdexec.ExitError(err)
+			}
+
+			status.Print(os.Stdout, allNamespaces)
+			if !status.Healthy() {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
 func tidbK9sCommand() *cli.Command {
 	return &cli.Command{
 		Name:    "k9s",
@@ -142,6 +388,8 @@ func tidbK9sCommand() *cli.Command {
 		Usage:   "k9s wrapper for TiDB",
 		Flags:   mdk8s.BaseK8sFlags,
 		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
 			namespace := cCtx.String("namespace")
@@ -152,17 +400,26 @@ func tidbK9sCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			namespace, allNamespaces, namespaceNote, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
 			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
 			args, err := builder.BuildK9sArgs(context, namespace, allNamespaces, cCtx.Args().Slice())
 			if err != nil {
 				return cli.Exit(fmt.Sprintf("Failed to build k9s args: %v", err), 1)
@@ -174,9 +431,19 @@ func tidbK9sCommand() *cli.Command {
 				return cli.Exit(fmt.Sprintf("cellauth check failed: %v", err), 1)
 			}
 
+			k9sConfigDir, useK9sConfig := mdk8s.K9sConfigDir(context)
 			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+				if useK9sConfig {
+					colorDebugPrintfln(context, "Using k9s config %s", k9sConfigDir)
+				}
 				colorDebugPrintfln(context, "%s %s", mdk8s.K9s, strings.Join(args, " "))
 			}
+			if useK9sConfig {
+				if err := os.Setenv("K9S_CONFIG_DIR", k9sConfigDir); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			}
 
 			_, err = script.Exec(fmt.Sprintf("%s %s", mdk8s.K9s, strings.Join(args, " "))).Stdout()
 			return mdexec.ExitError(err)
@@ -204,12 +471,12 @@ func tidbMysqlCommand() *cli.Command {
 			&cli.IntFlag{
 				Name:    "port",
 				Aliases: []string{"P"},
-				Value:   -1,
-				Usage:   "Port to forward to. Defaults to random 40xx.",
+				Value:   0,
+				Usage:   "Local port to forward to. Defaults to reusing an existing port-forward or a free port.",
 			},
 		),
 		Action: func(cCtx *cli.Context) error {
-			cfg := config.NewConfig()
+			cfg := config.FromMetadata(cCtx.App.Metadata)
 
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
@@ -221,19 +488,21 @@ func tidbMysqlCommand() *cli.Command {
 			debug := cCtx.Bool("debug")
 			assumeClusterAdmin := cCtx.Bool("assume-cluster-admin")
 
-			if port == -1 {
-				port = rand.Intn(100) + 4000
-			}
-
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, _, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -251,41 +520,26 @@ func tidbMysqlCommand() *cli.Command {
 			}
 			podName = fmt.Sprintf("%s-%d", podName, pod)
 			builder := NewTidbKubeBuilder()
-			portForwardCmd, _ := builder.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, []string{"port-forward", podName, fmt.Sprintf("%d:4000", port)})
-			cmd := exec.Command("kubectl", portForwardCmd...)
-			cmd.Stdin = os.Stdin
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			defer func() {
-				debugPrintln("Stopping port-forward...")
-				if cmd.Process != nil {
-					if err := cmd.Process.Kill(); err != nil {
-						debugPrintln("Error stopping port-forward:", err)
-					}
-				}
-			}()
-
-			if debug {
-				colorDebugPrintfln(context, "%s %s", "kubectl", strings.Join(portForwardCmd, " "))
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
 			}
-
-			portForwardErr := make(chan error)
-			go func() {
-				debugPrintfln("Starting port-forward from %s:4000 to %d", podName, port)
-				if err = cmd.Run(); err != nil {
-					debugPrintln(err)
-					portForwardErr <- err
-				}
-			}()
-
-			// Wait a bit for port-forward to establish or fail
-			select {
-			case err = <-portForwardErr:
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+				colorDebugPrintfln(context, "Forwarding %s:4000 via managed port-forward", podName)
+			}
+			pf, err := builder.EnsurePortForward(mdk8s.PortForwardOptions{
+				Context:            context,
+				Namespace:          namespace,
+				Target:             podName,
+				RemotePort:         4000,
+				LocalPort:          port,
+				AssumeClusterAdmin: assumeClusterAdmin,
+			})
+			if err != nil {
 				return mdexec.ExitError(fmt.Errorf("port-forward failed: %w", err))
-			case <-time.After(2 * time.Second):
-				// Port forward likely started, continue
 			}
+			port = pf.LocalPort
+			debugPrintfln("Using port-forward from %s:4000 to %d", pf.Target, port)
 
 			mysqlArgs := []string{"-h", "127.0.0.1", "-P", fmt.Sprintf("%d", port), "-u", "root", "-p" + rootPass, "--prompt=tidb> "}
 			redactedMysqlArgs := []string{"-h", "127.0.0.1", "-P", fmt.Sprintf("%d", port), "-u", "root", "-pPASS", "--prompt=tidb> "}
@@ -328,7 +582,7 @@ func tidbDmctlCommand() *cli.Command {
 			},
 		),
 		Action: func(cCtx *cli.Context) error {
-			cfg := config.NewConfig()
+			cfg := config.FromMetadata(cCtx.App.Metadata)
 
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
@@ -343,12 +597,18 @@ func tidbDmctlCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, _, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -384,9 +644,13 @@ func tidbDmctlCommand() *cli.Command {
 				assumeClusterAdmin = assumeClusterAdmin || cfg.EnableClusterAdminForTest
 			}
 			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
 			execArgs, _ := builder.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, []string{"exec", "-it", podName, "-c", container, "--", "bin/sh", "-c", dmctlCmd})
 
 			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
 				colorDebugPrintfln(context, "%s %s", "kubectl", strings.Join(execArgs, " "))
 			}
 
@@ -410,7 +674,7 @@ func tidbPdctlCommand() *cli.Command {
 			},
 		),
 		Action: func(cCtx *cli.Context) error {
-			cfg := config.NewConfig()
+			cfg := config.FromMetadata(cCtx.App.Metadata)
 
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
@@ -424,12 +688,18 @@ func tidbPdctlCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, _, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -450,9 +720,13 @@ func tidbPdctlCommand() *cli.Command {
 				assumeClusterAdmin = assumeClusterAdmin || cfg.EnableClusterAdminForTest
 			}
 			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
 			execArgs, _ := builder.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, []string{"exec", "-it", podName, "-c", container, "--", "bin/sh", "-c", pdctlCmd})
 
 			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
 				colorDebugPrintfln(context, "%s %s", "kubectl", strings.Join(execArgs, " "))
 			}
 
@@ -476,7 +750,7 @@ func ticdcCommand() *cli.Command {
 			},
 		),
 		Action: func(cCtx *cli.Context) error {
-			cfg := config.NewConfig()
+			cfg := config.FromMetadata(cCtx.App.Metadata)
 
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
@@ -490,12 +764,18 @@ func ticdcCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, _, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -516,9 +796,13 @@ func ticdcCommand() *cli.Command {
 				assumeClusterAdmin = assumeClusterAdmin || cfg.EnableClusterAdminForTest
 			}
 			builder := NewTidbKubeBuilder()
+			if err := builder.AddConfigSubstitutions(cfg.K8s.Substitutions); err != nil {
+				return cli.Exit(fmt.Sprintf("Failed to load substitutions: %v", err), 1)
+			}
 			args, _ := builder.BuildKubectlArgs(context, namespace, false, assumeClusterAdmin, []string{"exec", "-it", podName, "-c", "ticdc", "--", "bin/sh", "-c", cdcCmd})
 
 			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
 				colorDebugPrintfln(context, "%s %s", "kubectl", strings.Join(args, " "))
 			}
 
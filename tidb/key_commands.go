@@ -0,0 +1,219 @@
+package tidb
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/michaelmdeng/mdcli/tidb/keycodec"
+	"github.com/urfave/cli/v2"
+)
+
+func tidbKeyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "key",
+		Usage: "Decode and encode TiDB keys offline",
+		Subcommands: []*cli.Command{
+			tidbKeyDecodeCommand(),
+			tidbKeyEncodeCommand(),
+		},
+	}
+}
+
+// printKey writes the decoded key, one field per line.
+func printKey(w io.Writer, key *keycodec.Key) {
+	fmt.Fprintf(w, "Key:      %s\n", key)
+	fmt.Fprintf(w, "Kind:     %s\n", key.Kind)
+	fmt.Fprintf(w, "Encoded:  %t\n", key.Encoded)
+	if key.Kind != keycodec.KindOther {
+		fmt.Fprintf(w, "Table:    %d\n", key.TableID)
+	}
+	if key.Handle != nil {
+		fmt.Fprintf(w, "Handle:   %d\n", *key.Handle)
+	}
+	for _, d := range key.CommonHandle {
+		fmt.Fprintf(w, "Handle:   %s (%s)\n", d, d.Flag)
+	}
+	if key.Kind == keycodec.KindIndex {
+		fmt.Fprintf(w, "Index:    %d\n", key.IndexID)
+	}
+	for _, d := range key.IndexValues {
+		fmt.Fprintf(w, "Value:    %s (%s)\n", d, d.Flag)
+	}
+	if key.Timestamp != 0 {
+		fmt.Fprintf(w, "TS:       %d\n", key.Timestamp)
+	}
+	if len(key.Rest) > 0 {
+		fmt.Fprintf(w, "Rest:     %X\n", key.Rest)
+	}
+}
+
+func tidbKeyDecodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "decode",
+		Usage:     "Decode hex or escaped TiDB keys, ie. from slow logs, region boundaries or TiKV errors",
+		ArgsUsage: "<key>...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Output `FORMAT`, json for JSON",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() == 0 {
+				return cli.Exit("a key must be provided", 1)
+			}
+			output := cCtx.String("output")
+			if output != "" && output != "json" {
+				return cli.Exit(fmt.Sprintf("unknown output '%s', must be json", output), 1)
+			}
+
+			keys := make([]*keycodec.Key, 0, cCtx.NArg())
+			for _, input := range cCtx.Args().Slice() {
+				b, err := keycodec.ParseInput(input)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				key, err := keycodec.Decode(b)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("failed to decode %s: %v", input, err), 1)
+				}
+				keys = append(keys, key)
+			}
+
+			if output == "json" {
+				out, err := json.MarshalIndent(keys, "", "  ")
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			for i, key := range keys {
+				if i > 0 {
+					fmt.Println()
+				}
+				printKey(os.Stdout, key)
+			}
+			return nil
+		},
+	}
+}
+
+// parseDatum parses a value given on the command line, typed by a prefix of
+// int:, uint:, float:, str:, hex: or null. Untyped values are ints if they
+// parse as one, or otherwise strings.
+func parseDatum(value string) (keycodec.Datum, error) {
+	if strings.EqualFold(value, "null") {
+		return keycodec.Datum{Flag: keycodec.NilFlag}, nil
+	}
+
+	kind, v, typed := strings.Cut(value, ":")
+	if !typed {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return keycodec.Datum{Flag: keycodec.IntFlag, Value: i}, nil
+		}
+		return keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte(value)}, nil
+	}
+
+	switch kind {
+	case "int":
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return keycodec.Datum{}, fmt.Errorf("invalid int value '%s'", v)
+		}
+		return keycodec.Datum{Flag: keycodec.IntFlag, Value: i}, nil
+	case "uint":
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return keycodec.Datum{}, fmt.Errorf("invalid uint value '%s'", v)
+		}
+		return keycodec.Datum{Flag: keycodec.UintFlag, Value: u}, nil
+	case "float":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return keycodec.Datum{}, fmt.Errorf("invalid float value '%s'", v)
+		}
+		return keycodec.Datum{Flag: keycodec.FloatFlag, Value: f}, nil
+	case "str":
+		return keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte(v)}, nil
+	case "hex":
+		b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+		if err != nil {
+			return keycodec.Datum{}, fmt.Errorf("invalid hex value '%s'", v)
+		}
+		return keycodec.Datum{Flag: keycodec.BytesFlag, Value: b}, nil
+	default:
+		// Untyped strings may contain colons, ie. times
+		return keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte(value)}, nil
+	}
+}
+
+func tidbKeyEncodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "encode",
+		Usage: "Encode a TiDB table, row or index key, printing the raw and memcomparable encoded keys",
+		Flags: []cli.Flag{
+			&cli.Int64Flag{
+				Name:     "table",
+				Usage:    "Table `ID`",
+				Required: true,
+			},
+			&cli.Int64Flag{
+				Name:  "row",
+				Usage: "Int `HANDLE` of the row",
+			},
+			&cli.Int64Flag{
+				Name:  "index",
+				Usage: "Index `ID`",
+				Value: 0,
+			},
+			&cli.StringSliceFlag{
+				Name:  "value",
+				Usage: "Index or common handle `VALUE`, in column order. Typed by a prefix of int:, uint:, float:, str: or hex:, ie. str:123 for a VARCHAR, or NULL. Untyped values are ints if numeric and otherwise strings",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			tableID := cCtx.Int64("table")
+			values := make([]keycodec.Datum, 0)
+			for _, value := range cCtx.StringSlice("value") {
+				d, err := parseDatum(value)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				values = append(values, d)
+			}
+
+			var raw []byte
+			var err error
+			switch {
+			case cCtx.IsSet("row") && cCtx.IsSet("index"):
+				return cli.Exit("either --row or --index must be provided, not both", 1)
+			case cCtx.IsSet("row") && len(values) > 0:
+				return cli.Exit("--value can't be used with --row, use --value alone for a common handle", 1)
+			case cCtx.IsSet("row"):
+				raw = keycodec.RecordKey(tableID, cCtx.Int64("row"))
+			case cCtx.IsSet("index"):
+				raw, err = keycodec.IndexKey(tableID, cCtx.Int64("index"), values)
+			case len(values) > 0:
+				raw, err = keycodec.CommonHandleRecordKey(tableID, values)
+			default:
+				raw = keycodec.TablePrefix(tableID)
+			}
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Printf("Raw:      %s\n", strings.ToUpper(hex.EncodeToString(raw)))
+			fmt.Printf("Encoded:  %s\n", strings.ToUpper(hex.EncodeToString(keycodec.EncodeBytes(raw))))
+			return nil
+		},
+	}
+}
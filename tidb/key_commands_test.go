@@ -0,0 +1,93 @@
+package tidb
+
+import (
+	"io"
+	"testing"
+
+	"github.com/michaelmdeng/mdcli/tidb/keycodec"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestParseDatum(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected keycodec.Datum
+	}{
+		{"NULL", keycodec.Datum{Flag: keycodec.NilFlag}},
+		{"123", keycodec.Datum{Flag: keycodec.IntFlag, Value: int64(123)}},
+		{"abc", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte("abc")}},
+		{"12:30:00", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte("12:30:00")}},
+		{"int:-5", keycodec.Datum{Flag: keycodec.IntFlag, Value: int64(-5)}},
+		{"uint:18446744073709551615", keycodec.Datum{Flag: keycodec.UintFlag, Value: uint64(18446744073709551615)}},
+		{"float:1.5", keycodec.Datum{Flag: keycodec.FloatFlag, Value: 1.5}},
+		{"str:123", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte("123")}},
+		{"str:null", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte("null")}},
+		{"hex:00FF", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte{0x00, 0xFF}}},
+		{"hex:0x0a", keycodec.Datum{Flag: keycodec.BytesFlag, Value: []byte{0x0A}}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			d, err := parseDatum(test.input)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d)
+		})
+	}
+
+	for _, input := range []string{"int:abc", "uint:-1", "float:x", "hex:zz"} {
+		_, err := parseDatum(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseDatumIndexKey(t *testing.T) {
+	for _, input := range []string{"int:7", "uint:7", "float:7.5", "str:123", "hex:ABCD"} {
+		t.Run(input, func(t *testing.T) {
+			d, err := parseDatum(input)
+			assert.NoError(t, err)
+
+			raw, err := keycodec.IndexKey(45, 2, []keycodec.Datum{d})
+			assert.NoError(t, err)
+			key, err := keycodec.Decode(raw)
+			assert.NoError(t, err)
+			assert.Equal(t, []keycodec.Datum{d}, key.IndexValues)
+		})
+	}
+}
+
+func TestTidbKeyEncodeCommandErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		expectedErr string
+	}{
+		{
+			name:        "Row and index",
+			args:        []string{"--table", "45", "--row", "1", "--index", "2"},
+			expectedErr: "not both",
+		},
+		{
+			name:        "Row and value",
+			args:        []string{"--table", "45", "--row", "1", "--value", "abc"},
+			expectedErr: "--value can't be used with --row",
+		},
+		{
+			name:        "Non-integer row",
+			args:        []string{"--table", "45", "--row", "abc"},
+			expectedErr: "invalid value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := cli.NewApp()
+			app.ExitErrHandler = func(_ *cli.Context, err error) {}
+			app.ErrWriter = io.Discard
+			app.Writer = io.Discard
+			app.Commands = []*cli.Command{tidbKeyEncodeCommand()}
+
+			err := app.Run(append([]string{"mdcli", "encode"}, tc.args...))
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package keycodec encodes and decodes TiDB keys as stored in TiKV.
+package keycodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	signMask uint64 = 0x8000000000000000
+
+	encGroupSize = 8
+	encMarker    = byte(0xFF)
+	encPad       = byte(0x0)
+)
+
+// EncodeBytes encodes b in memcomparable format, as TiKV stores keys, in
+// groups of 8 bytes each followed by a marker of 0xFF minus the padding.
+func EncodeBytes(b []byte) []byte {
+	out := make([]byte, 0, (len(b)/encGroupSize+1)*(encGroupSize+1))
+	for idx := 0; idx <= len(b); idx += encGroupSize {
+		remain := len(b) - idx
+		padCount := 0
+		if remain >= encGroupSize {
+			out = append(out, b[idx:idx+encGroupSize]...)
+		} else {
+			padCount = encGroupSize - remain
+			out = append(out, b[idx:]...)
+			for range padCount {
+				out = append(out, encPad)
+			}
+		}
+		out = append(out, encMarker-byte(padCount))
+	}
+	return out
+}
+
+// DecodeBytes decodes memcomparable bytes from the start of b, returning the
+// remaining bytes.
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	out := make([]byte, 0, len(b))
+	for {
+		if len(b) < encGroupSize+1 {
+			return nil, nil, errors.New("insufficient bytes to decode memcomparable bytes")
+		}
+
+		group := b[:encGroupSize]
+		marker := b[encGroupSize]
+		b = b[encGroupSize+1:]
+
+		padCount := encMarker - marker
+		if padCount > encGroupSize {
+			return nil, nil, fmt.Errorf("invalid memcomparable marker byte %#x", marker)
+		}
+
+		realGroupSize := encGroupSize - int(padCount)
+		out = append(out, group[:realGroupSize]...)
+		if padCount != 0 {
+			for _, pad := range group[realGroupSize:] {
+				if pad != encPad {
+					return nil, nil, fmt.Errorf("invalid memcomparable padding byte %#x", pad)
+				}
+			}
+			return out, b, nil
+		}
+	}
+}
+
+// decodeBytesPrefix decodes memcomparable bytes which may be truncated, keeping
+// the bytes of an incomplete final group as they are.
+func decodeBytesPrefix(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for len(b) > encGroupSize {
+		padCount := int(encMarker - b[encGroupSize])
+		if padCount > encGroupSize {
+			padCount = 0
+		}
+		out = append(out, b[:encGroupSize-padCount]...)
+		b = b[encGroupSize+1:]
+		if padCount != 0 {
+			return out
+		}
+	}
+	return append(out, b...)
+}
+
+// EncodeInt encodes v so that the encoding of signed ints sorts as they do.
+func EncodeInt(b []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(v)^signMask)
+}
+
+// DecodeInt decodes an int encoded by EncodeInt from the start of b.
+func DecodeInt(b []byte) (int64, []byte, error) {
+	if len(b) < 8 {
+		return 0, nil, errors.New("insufficient bytes to decode int")
+	}
+	return int64(binary.BigEndian.Uint64(b) ^ signMask), b[8:], nil
+}
+
+// EncodeUint encodes v big endian.
+func EncodeUint(b []byte, v uint64) []byte {
+	return binary.BigEndian.AppendUint64(b, v)
+}
+
+// DecodeUint decodes a big endian uint from the start of b.
+func DecodeUint(b []byte) (uint64, []byte, error) {
+	if len(b) < 8 {
+		return 0, nil, errors.New("insufficient bytes to decode uint")
+	}
+	return binary.BigEndian.Uint64(b), b[8:], nil
+}
+
+// EncodeFloat encodes v so that the encoding of floats sorts as they do.
+func EncodeFloat(b []byte, v float64) []byte {
+	u := math.Float64bits(v)
+	if v >= 0 {
+		u |= signMask
+	} else {
+		u = ^u
+	}
+	return EncodeUint(b, u)
+}
+
+// DecodeFloat decodes a float encoded by EncodeFloat from the start of b.
+func DecodeFloat(b []byte) (float64, []byte, error) {
+	u, b, err := DecodeUint(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if u&signMask > 0 {
+		u &= ^signMask
+	} else {
+		u = ^u
+	}
+	return math.Float64frombits(u), b, nil
+}
+
+// decodeTimestamp decodes the descending encoded MVCC timestamp suffixed to
+// keys in TiKV.
+func decodeTimestamp(b []byte) (uint64, error) {
+	u, rest, err := DecodeUint(b)
+	if err != nil {
+		return 0, err
+	}
+	if len(rest) > 0 {
+		return 0, errors.New("unexpected bytes after timestamp")
+	}
+	return ^u, nil
+}
@@ -0,0 +1,189 @@
+package keycodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Flag is the type prefix of an encoded datum.
+type Flag byte
+
+const (
+	NilFlag          Flag = 0
+	BytesFlag        Flag = 1
+	CompactBytesFlag Flag = 2
+	IntFlag          Flag = 3
+	UintFlag         Flag = 4
+	FloatFlag        Flag = 5
+	DecimalFlag      Flag = 6
+	DurationFlag     Flag = 7
+	VarintFlag       Flag = 8
+	UvarintFlag      Flag = 9
+	JSONFlag         Flag = 10
+	MaxFlag          Flag = 250
+)
+
+var flagNames = map[Flag]string{
+	NilFlag:          "nil",
+	BytesFlag:        "bytes",
+	CompactBytesFlag: "compactBytes",
+	IntFlag:          "int",
+	UintFlag:         "uint",
+	FloatFlag:        "float",
+	DecimalFlag:      "decimal",
+	DurationFlag:     "duration",
+	VarintFlag:       "varint",
+	UvarintFlag:      "uvarint",
+	JSONFlag:         "json",
+	MaxFlag:          "max",
+}
+
+func (f Flag) String() string {
+	if name, ok := flagNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("flag(%d)", byte(f))
+}
+
+// Datum is a decoded column value of an index or common handle. Value is nil,
+// []byte, int64, uint64, float64 or time.Duration depending on Flag.
+type Datum struct {
+	Flag  Flag
+	Value any
+}
+
+func (d Datum) String() string {
+	switch v := d.Value.(type) {
+	case nil:
+		if d.Flag == MaxFlag {
+			return "MAX"
+		}
+		return "NULL"
+	case []byte:
+		if utf8.Valid(v) {
+			return strconv.Quote(string(v))
+		}
+		return fmt.Sprintf("0x%X", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// MarshalText renders the datum as in String, so JSON output is readable.
+func (d Datum) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// EncodeDatum appends the datum in memcomparable format, as in index keys.
+// Compact bytes, varints and uvarints are not memcomparable and are only used
+// in values.
+func EncodeDatum(b []byte, d Datum) ([]byte, error) {
+	switch d.Flag {
+	case NilFlag, MaxFlag:
+		return append(b, byte(d.Flag)), nil
+	case BytesFlag:
+		v, ok := d.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("bytes datum has %T value", d.Value)
+		}
+		return append(append(b, byte(BytesFlag)), EncodeBytes(v)...), nil
+	case IntFlag:
+		v, ok := d.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("int datum has %T value", d.Value)
+		}
+		return EncodeInt(append(b, byte(IntFlag)), v), nil
+	case UintFlag:
+		v, ok := d.Value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("uint datum has %T value", d.Value)
+		}
+		return EncodeUint(append(b, byte(UintFlag)), v), nil
+	case FloatFlag:
+		v, ok := d.Value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("float datum has %T value", d.Value)
+		}
+		return EncodeFloat(append(b, byte(FloatFlag)), v), nil
+	case DurationFlag:
+		v, ok := d.Value.(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("duration datum has %T value", d.Value)
+		}
+		return EncodeInt(append(b, byte(DurationFlag)), int64(v)), nil
+	default:
+		return nil, fmt.Errorf("encoding %s datums is not supported", d.Flag)
+	}
+}
+
+// DecodeDatum decodes a datum from the start of b, returning the remaining
+// bytes.
+func DecodeDatum(b []byte) (Datum, []byte, error) {
+	if len(b) == 0 {
+		return Datum{}, nil, errors.New("insufficient bytes to decode datum")
+	}
+
+	flag := Flag(b[0])
+	b = b[1:]
+	switch flag {
+	case NilFlag, MaxFlag:
+		return Datum{Flag: flag}, b, nil
+	case BytesFlag:
+		v, rest, err := DecodeBytes(b)
+		return Datum{Flag: flag, Value: v}, rest, err
+	case CompactBytesFlag:
+		length, n := binary.Varint(b)
+		if n <= 0 || length < 0 || int64(len(b)-n) < length {
+			return Datum{}, nil, errors.New("insufficient bytes to decode compact bytes")
+		}
+		return Datum{Flag: flag, Value: b[n : n+int(length)]}, b[n+int(length):], nil
+	case IntFlag:
+		v, rest, err := DecodeInt(b)
+		return Datum{Flag: flag, Value: v}, rest, err
+	case UintFlag:
+		v, rest, err := DecodeUint(b)
+		return Datum{Flag: flag, Value: v}, rest, err
+	case FloatFlag:
+		v, rest, err := DecodeFloat(b)
+		return Datum{Flag: flag, Value: v}, rest, err
+	case DurationFlag:
+		v, rest, err := DecodeInt(b)
+		return Datum{Flag: flag, Value: time.Duration(v)}, rest, err
+	case VarintFlag:
+		v, n := binary.Varint(b)
+		if n <= 0 {
+			return Datum{}, nil, errors.New("invalid varint")
+		}
+		return Datum{Flag: flag, Value: v}, b[n:], nil
+	case UvarintFlag:
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return Datum{}, nil, errors.New("invalid uvarint")
+		}
+		return Datum{Flag: flag, Value: v}, b[n:], nil
+	default:
+		return Datum{}, nil, fmt.Errorf("decoding %s datums is not supported", flag)
+	}
+}
+
+// DecodeDatums decodes datums until b is exhausted or a datum can't be
+// decoded, returning the undecoded bytes.
+func DecodeDatums(b []byte) ([]Datum, []byte) {
+	datums := make([]Datum, 0)
+	for len(b) > 0 {
+		d, rest, err := DecodeDatum(b)
+		if err != nil {
+			break
+		}
+		datums = append(datums, d)
+		b = rest
+	}
+	if len(b) == 0 {
+		return datums, nil
+	}
+	return datums, b
+}
@@ -0,0 +1,272 @@
+package keycodec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	tablePrefix  = []byte{'t'}
+	recordSep    = []byte("_r")
+	indexSep     = []byte("_i")
+	dataPrefix   = byte('z')
+	tableKeySize = len(tablePrefix) + 8
+)
+
+// Kind is the layout of a TiDB key.
+type Kind string
+
+const (
+	// KindTable is a table prefix, ie. t{tableID}
+	KindTable Kind = "table"
+	// KindRecord is a row key, ie. t{tableID}_r{handle}
+	KindRecord Kind = "record"
+	// KindIndex is an index key, ie. t{tableID}_i{indexID}{values}
+	KindIndex Kind = "index"
+	// KindOther is a key outside the table keyspace, ie. meta keys
+	KindOther Kind = "other"
+)
+
+// Key is a decoded TiDB key.
+type Key struct {
+	Kind Kind `json:"kind"`
+	// Whether the key was memcomparable encoded, as in TiKV and PD
+	Encoded bool `json:"encoded"`
+	// Whether the key had the z prefix of TiKV data keys
+	DataPrefix bool  `json:"dataPrefix,omitempty"`
+	TableID    int64 `json:"tableId,omitempty"`
+	// Handle of a record with an int handle
+	Handle *int64 `json:"handle,omitempty"`
+	// Handle of a record in a clustered table with a common handle
+	CommonHandle []Datum `json:"commonHandle,omitempty"`
+	IndexID      int64   `json:"indexId,omitempty"`
+	IndexValues  []Datum `json:"indexValues,omitempty"`
+	// MVCC timestamp suffixed to the key, if any
+	Timestamp uint64 `json:"timestamp,omitempty"`
+	// Bytes which could not be decoded, ie. of a truncated region boundary
+	Rest HexBytes `json:"rest,omitempty"`
+}
+
+// HexBytes are bytes rendered as uppercase hex in JSON.
+type HexBytes []byte
+
+func (h HexBytes) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(h))), nil
+}
+
+func (k *Key) String() string {
+	var b strings.Builder
+	switch k.Kind {
+	case KindOther:
+		fmt.Fprintf(&b, "%q", k.Rest)
+		return b.String()
+	case KindTable:
+		fmt.Fprintf(&b, "t%d", k.TableID)
+	case KindRecord:
+		fmt.Fprintf(&b, "t%d_r", k.TableID)
+		if k.Handle != nil {
+			fmt.Fprintf(&b, "%d", *k.Handle)
+		} else {
+			b.WriteString(formatDatums(k.CommonHandle))
+		}
+	case KindIndex:
+		fmt.Fprintf(&b, "t%d_i%d%s", k.TableID, k.IndexID, formatDatums(k.IndexValues))
+	}
+	if len(k.Rest) > 0 {
+		fmt.Fprintf(&b, " +%X", k.Rest)
+	}
+	if k.Timestamp != 0 {
+		fmt.Fprintf(&b, " @%d", k.Timestamp)
+	}
+	return b.String()
+}
+
+func formatDatums(datums []Datum) string {
+	values := make([]string, 0, len(datums))
+	for _, d := range datums {
+		values = append(values, d.String())
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+// ParseInput parses a key given as hex, ie. 7480000000000000FF2D, or as an
+// escaped string as printed in TiKV logs and errors, ie. t\200\000\377.
+func ParseInput(input string) ([]byte, error) {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(strings.TrimPrefix(input, "0x"), "0X")
+	if input == "" {
+		return nil, errors.New("key is empty")
+	}
+
+	if b, err := hex.DecodeString(input); err == nil {
+		return b, nil
+	}
+	return unescape(input)
+}
+
+// unescape unescapes the octal, hex and C escapes used for keys in TiKV and
+// TiDB logs.
+func unescape(s string) ([]byte, error) {
+	s = strings.Trim(s, `"`)
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+
+		i++
+		if i >= len(s) {
+			return nil, errors.New("key ends with an incomplete escape")
+		}
+		switch c := s[i]; {
+		case c >= '0' && c <= '7':
+			end := i
+			for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			v, err := strconv.ParseUint(s[i:end], 8, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid octal escape \\%s", s[i:end])
+			}
+			out = append(out, byte(v))
+			i = end - 1
+		case c == 'x':
+			if i+2 >= len(s) {
+				return nil, errors.New("key ends with an incomplete hex escape")
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex escape \\x%s", s[i+1:i+3])
+			}
+			out = append(out, byte(v))
+			i += 2
+		case c == 'n':
+			out = append(out, '\n')
+		case c == 'r':
+			out = append(out, '\r')
+		case c == 't':
+			out = append(out, '\t')
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Decode decodes a raw or memcomparable encoded TiDB key, optionally with the z
+// data prefix and MVCC timestamp suffix that TiKV adds.
+func Decode(b []byte) (*Key, error) {
+	if len(b) == 0 {
+		return nil, errors.New("key is empty")
+	}
+
+	key := &Key{}
+	if b[0] == dataPrefix {
+		key.DataPrefix = true
+		b = b[1:]
+	}
+
+	// Encoded keys are tried first, as a raw key is rarely valid memcomparable
+	// bytes but an encoded key always starts with the raw table prefix too.
+	if raw, rest, err := DecodeBytes(b); err == nil && bytes.HasPrefix(raw, tablePrefix) && (len(rest) == 0 || len(rest) == 8) {
+		if len(rest) == 8 {
+			ts, err := decodeTimestamp(rest)
+			if err != nil {
+				return nil, err
+			}
+			key.Timestamp = ts
+		}
+		key.Encoded = true
+		b = raw
+	} else if bytes.HasPrefix(b, tablePrefix) && len(b) > encGroupSize && b[encGroupSize] == encMarker {
+		// A truncated encoded key, ie. a region boundary
+		key.Encoded = true
+		b = decodeBytesPrefix(b)
+	}
+
+	if !bytes.HasPrefix(b, tablePrefix) || len(b) < tableKeySize {
+		key.Kind = KindOther
+		key.Rest = b
+		return key, nil
+	}
+
+	tableID, b, err := DecodeInt(b[len(tablePrefix):])
+	if err != nil {
+		return nil, err
+	}
+	key.TableID = tableID
+
+	switch {
+	case len(b) == 0:
+		key.Kind = KindTable
+	case bytes.HasPrefix(b, recordSep):
+		key.Kind = KindRecord
+		b = b[len(recordSep):]
+		if len(b) == 8 {
+			handle, _, _ := DecodeInt(b)
+			key.Handle = &handle
+		} else {
+			key.CommonHandle, key.Rest = DecodeDatums(b)
+		}
+	case bytes.HasPrefix(b, indexSep):
+		key.Kind = KindIndex
+		b = b[len(indexSep):]
+		indexID, rest, err := DecodeInt(b)
+		if err != nil {
+			key.Rest = b
+			return key, nil
+		}
+		key.IndexID = indexID
+		key.IndexValues, key.Rest = DecodeDatums(rest)
+	default:
+		key.Kind = KindTable
+		key.Rest = b
+	}
+	return key, nil
+}
+
+// TablePrefix returns the raw key prefix of the table's rows and indexes.
+func TablePrefix(tableID int64) []byte {
+	return EncodeInt(append([]byte{}, tablePrefix...), tableID)
+}
+
+// RecordPrefix returns the raw key prefix of the table's rows.
+func RecordPrefix(tableID int64) []byte {
+	return append(TablePrefix(tableID), recordSep...)
+}
+
+// RecordKey returns the raw key of the row with an int handle.
+func RecordKey(tableID, handle int64) []byte {
+	return EncodeInt(RecordPrefix(tableID), handle)
+}
+
+// CommonHandleRecordKey returns the raw key of the row in a clustered table
+// with a common handle.
+func CommonHandleRecordKey(tableID int64, handle []Datum) ([]byte, error) {
+	b := RecordPrefix(tableID)
+	for _, d := range handle {
+		var err error
+		if b, err = EncodeDatum(b, d); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// IndexKey returns the raw key of the index entry, or its prefix if values
+// are missing.
+func IndexKey(tableID, indexID int64, values []Datum) ([]byte, error) {
+	b := EncodeInt(append(TablePrefix(tableID), indexSep...), indexID)
+	for _, d := range values {
+		var err error
+		if b, err = EncodeDatum(b, d); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
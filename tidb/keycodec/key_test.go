@@ -0,0 +1,241 @@
+package keycodec
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	assert.NoError(t, err)
+	return b
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestEncodeBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "0000000000000000F7"},
+		{"abc", "6162630000000000FA"},
+		{"abcdefgh", "6162636465666768FF0000000000000000F7"},
+		{"abcdefghi", "6162636465666768FF6900000000000000F8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			encoded := EncodeBytes([]byte(test.input))
+			assert.Equal(t, test.expected, strings.ToUpper(hex.EncodeToString(encoded)))
+
+			decoded, rest, err := DecodeBytes(append(encoded, 'x'))
+			assert.NoError(t, err)
+			assert.Equal(t, test.input, string(decoded))
+			assert.Equal(t, []byte("x"), rest)
+		})
+	}
+
+	_, _, err := DecodeBytes(mustHex(t, "6162636465666768FF"))
+	assert.Error(t, err)
+	_, _, err = DecodeBytes(mustHex(t, "6162630000000001FA"))
+	assert.Error(t, err)
+}
+
+func TestEncodeNumbers(t *testing.T) {
+	for _, v := range []int64{math.MinInt64, -1, 0, 1, 100, math.MaxInt64} {
+		decoded, rest, err := DecodeInt(EncodeInt(nil, v))
+		assert.NoError(t, err)
+		assert.Empty(t, rest)
+		assert.Equal(t, v, decoded)
+	}
+	assert.Equal(t, "8000000000000064", hex.EncodeToString(EncodeInt(nil, 100)))
+	assert.Equal(t, "7fffffffffffffff", hex.EncodeToString(EncodeInt(nil, -1)))
+
+	for _, v := range []float64{-1.5, 0, 3.25, math.Inf(1)} {
+		decoded, _, err := DecodeFloat(EncodeFloat(nil, v))
+		assert.NoError(t, err)
+		assert.Equal(t, v, decoded)
+	}
+	// Encoded floats sort as the floats do
+	assert.Less(t, string(EncodeFloat(nil, -2)), string(EncodeFloat(nil, -1)))
+	assert.Less(t, string(EncodeFloat(nil, -1)), string(EncodeFloat(nil, 1)))
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Key
+		str      string
+	}{
+		{
+			name:     "encoded record",
+			input:    "7480000000000000FF2D5F728000000000FF0000640000000000FA",
+			expected: Key{Kind: KindRecord, Encoded: true, TableID: 45, Handle: int64Ptr(100)},
+			str:      "t45_r100",
+		},
+		{
+			name:     "raw record",
+			input:    "74800000000000002D5F728000000000000064",
+			expected: Key{Kind: KindRecord, TableID: 45, Handle: int64Ptr(100)},
+			str:      "t45_r100",
+		},
+		{
+			name:     "negative handle",
+			input:    "74800000000000002D5F727FFFFFFFFFFFFFFF",
+			expected: Key{Kind: KindRecord, TableID: 45, Handle: int64Ptr(-1)},
+			str:      "t45_r-1",
+		},
+		{
+			name:     "escaped record",
+			input:    `t\200\000\000\000\000\000\000\377-_r\200\000\000\000\000\377\000\000d\000\000\000\000\000\372`,
+			expected: Key{Kind: KindRecord, Encoded: true, TableID: 45, Handle: int64Ptr(100)},
+			str:      "t45_r100",
+		},
+		{
+			name:     "data prefix and timestamp",
+			input:    "7A7480000000000000FF2D5F728000000000FF0000640000000000FA" + "F9C1D5D5FB03FFFF",
+			expected: Key{Kind: KindRecord, Encoded: true, DataPrefix: true, TableID: 45, Handle: int64Ptr(100), Timestamp: 449843372744245248},
+			str:      "t45_r100 @449843372744245248",
+		},
+		{
+			name:     "encoded table prefix",
+			input:    "7480000000000000FF2D00000000000000F8",
+			expected: Key{Kind: KindTable, Encoded: true, TableID: 45},
+			str:      "t45",
+		},
+		{
+			name:  "raw index",
+			input: "7480000000000000" + "2D" + "5F69" + "8000000000000002" + "03800000000000000A" + "016162630000000000FA",
+			expected: Key{Kind: KindIndex, TableID: 45, IndexID: 2, IndexValues: []Datum{
+				{Flag: IntFlag, Value: int64(10)},
+				{Flag: BytesFlag, Value: []byte("abc")},
+			}},
+			str: `t45_i2(10, "abc")`,
+		},
+		{
+			name:  "index with null and uint",
+			input: "74800000000000002D5F6980000000000000010004000000000000002A",
+			expected: Key{Kind: KindIndex, TableID: 45, IndexID: 1, IndexValues: []Datum{
+				{Flag: NilFlag},
+				{Flag: UintFlag, Value: uint64(42)},
+			}},
+			str: "t45_i1(NULL, 42)",
+		},
+		{
+			name:  "common handle",
+			input: "74800000000000002D5F72016B65790000000000FA03800000000000000A",
+			expected: Key{Kind: KindRecord, TableID: 45, CommonHandle: []Datum{
+				{Flag: BytesFlag, Value: []byte("key")},
+				{Flag: IntFlag, Value: int64(10)},
+			}},
+			str: `t45_r("key", 10)`,
+		},
+		{
+			name:  "truncated index",
+			input: "74800000000000002D5F69800000000000000103800000",
+			expected: Key{Kind: KindIndex, TableID: 45, IndexID: 1, IndexValues: []Datum{},
+				Rest: mustHex(t, "03800000")},
+			str: "t45_i1() +03800000",
+		},
+		{
+			name:     "truncated encoded key",
+			input:    "7480000000000000FF2D5F728000",
+			expected: Key{Kind: KindRecord, Encoded: true, TableID: 45, CommonHandle: []Datum{}, Rest: mustHex(t, "8000")},
+			str:      "t45_r() +8000",
+		},
+		{
+			name:     "meta key",
+			input:    "6D44423A3100000000FB",
+			expected: Key{Kind: KindOther, Rest: mustHex(t, "6D44423A3100000000FB")},
+			str:      `"mDB:1\x00\x00\x00\x00\xfb"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := ParseInput(test.input)
+			assert.NoError(t, err)
+
+			key, err := Decode(b)
+			assert.NoError(t, err)
+			assert.Equal(t, &test.expected, key)
+			assert.Equal(t, test.str, key.String())
+		})
+	}
+}
+
+func TestParseInput(t *testing.T) {
+	b, err := ParseInput(" 0x7480 ")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x74, 0x80}, b)
+
+	b, err = ParseInput(`"t\x80\200_r"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'t', 0x80, 0x80, '_', 'r'}, b)
+
+	_, err = ParseInput(`t\x8`)
+	assert.Error(t, err)
+	_, err = ParseInput("")
+	assert.Error(t, err)
+}
+
+func TestEncodeKeys(t *testing.T) {
+	assert.Equal(t, "7480000000000000FF2D5F728000000000FF0000640000000000FA", strings.ToUpper(hex.EncodeToString(EncodeBytes(RecordKey(45, 100)))))
+	assert.Equal(t, "74800000000000002D5F72", strings.ToUpper(hex.EncodeToString(RecordPrefix(45))))
+
+	values := []Datum{
+		{Flag: IntFlag, Value: int64(10)},
+		{Flag: BytesFlag, Value: []byte("abc")},
+		{Flag: FloatFlag, Value: 1.5},
+		{Flag: DurationFlag, Value: time.Second},
+		{Flag: UintFlag, Value: uint64(7)},
+		{Flag: NilFlag},
+	}
+	raw, err := IndexKey(45, 2, values)
+	assert.NoError(t, err)
+
+	key, err := Decode(EncodeBytes(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, &Key{Kind: KindIndex, Encoded: true, TableID: 45, IndexID: 2, IndexValues: values}, key)
+
+	raw, err = CommonHandleRecordKey(45, values[:2])
+	assert.NoError(t, err)
+	key, err = Decode(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, values[:2], key.CommonHandle)
+
+	_, err = IndexKey(45, 2, []Datum{{Flag: IntFlag, Value: "10"}})
+	assert.Error(t, err)
+	_, err = IndexKey(45, 2, []Datum{{Flag: DecimalFlag}})
+	assert.Error(t, err)
+}
+
+func TestDecodeDatum(t *testing.T) {
+	d, rest, err := DecodeDatum(mustHex(t, "0206616263FF"))
+	assert.NoError(t, err)
+	assert.Equal(t, Datum{Flag: CompactBytesFlag, Value: []byte("abc")}, d)
+	assert.Equal(t, []byte{0xFF}, rest)
+
+	d, _, err = DecodeDatum(mustHex(t, "0803"))
+	assert.NoError(t, err)
+	assert.Equal(t, Datum{Flag: VarintFlag, Value: int64(-2)}, d)
+
+	d, _, err = DecodeDatum(mustHex(t, "09AC02"))
+	assert.NoError(t, err)
+	assert.Equal(t, Datum{Flag: UvarintFlag, Value: uint64(300)}, d)
+
+	_, _, err = DecodeDatum(mustHex(t, "0A00"))
+	assert.EqualError(t, err, "decoding json datums is not supported")
+
+	assert.Equal(t, "0xFF00", Datum{Flag: BytesFlag, Value: []byte{0xFF, 0x00}}.String())
+	assert.Equal(t, "MAX", Datum{Flag: MaxFlag}.String())
+}
@@ -0,0 +1,345 @@
+// Package model has types for the TidbCluster resources of the TiDB operator,
+// as returned by `kubectl get tc -o json`.
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query runs kubectl get with the args and decodes the JSON output into v.
+type Query func(args []string, v any) error
+
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	UID         string            `json:"uid,omitempty"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type TidbCluster struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     TidbClusterSpec   `json:"spec"`
+	Status   TidbClusterStatus `json:"status"`
+}
+
+type TidbClusterList struct {
+	Items []TidbCluster `json:"items"`
+}
+
+type TidbClusterSpec struct {
+	Version    string         `json:"version"`
+	Paused     bool           `json:"paused"`
+	PD         *ComponentSpec `json:"pd"`
+	TiKV       *ComponentSpec `json:"tikv"`
+	TiDB       *ComponentSpec `json:"tidb"`
+	TiCDC      *ComponentSpec `json:"ticdc"`
+	TiFlash    *ComponentSpec `json:"tiflash"`
+	TLSCluster *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"tlsCluster"`
+}
+
+// ComponentSpec has the spec fields shared by the components. Their config is
+// left undecoded.
+type ComponentSpec struct {
+	Replicas  int    `json:"replicas"`
+	BaseImage string `json:"baseImage"`
+	Version   string `json:"version"`
+}
+
+type TidbClusterStatus struct {
+	ClusterID  string      `json:"clusterID"`
+	Conditions []Condition `json:"conditions"`
+	PD         PDStatus    `json:"pd"`
+	TiKV       TiKVStatus  `json:"tikv"`
+	TiDB       TiDBStatus  `json:"tidb"`
+	TiCDC      TiCDCStatus `json:"ticdc"`
+	TiFlash    TiKVStatus  `json:"tiflash"`
+}
+
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// StatefulSetStatus is the status of a component's StatefulSet.
+type StatefulSetStatus struct {
+	Replicas        int    `json:"replicas"`
+	ReadyReplicas   int    `json:"readyReplicas"`
+	CurrentReplicas int    `json:"currentReplicas"`
+	UpdatedReplicas int    `json:"updatedReplicas"`
+	CurrentRevision string `json:"currentRevision"`
+	UpdateRevision  string `json:"updateRevision"`
+}
+
+type PDStatus struct {
+	Synced      bool                `json:"synced"`
+	Phase       string              `json:"phase"`
+	Image       string              `json:"image"`
+	StatefulSet *StatefulSetStatus  `json:"statefulSet"`
+	Leader      PDMember            `json:"leader"`
+	Members     map[string]PDMember `json:"members"`
+}
+
+type PDMember struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	ClientURL string `json:"clientURL"`
+	Health    bool   `json:"health"`
+}
+
+// TiKVStatus is the status of TiKV, or of TiFlash which shares its shape.
+type TiKVStatus struct {
+	Synced          bool                 `json:"synced"`
+	Phase           string               `json:"phase"`
+	Image           string               `json:"image"`
+	StatefulSet     *StatefulSetStatus   `json:"statefulSet"`
+	Stores          map[string]TiKVStore `json:"stores"`
+	TombstoneStores map[string]TiKVStore `json:"tombstoneStores"`
+	FailureStores   map[string]struct {
+		PodName string `json:"podName"`
+		StoreID string `json:"storeID"`
+	} `json:"failureStores"`
+	// Pods with an evict-leader annotation being processed, by pod name
+	EvictLeader map[string]struct {
+		BeginTime time.Time `json:"beginTime"`
+		Value     string    `json:"value"`
+	} `json:"evictLeader"`
+}
+
+const (
+	StoreStateUp        = "Up"
+	StoreStateDown      = "Down"
+	StoreStateOffline   = "Offline"
+	StoreStateTombstone = "Tombstone"
+)
+
+type TiKVStore struct {
+	ID                string    `json:"id"`
+	PodName           string    `json:"podName"`
+	IP                string    `json:"ip"`
+	LeaderCount       int       `json:"leaderCount"`
+	State             string    `json:"state"`
+	LastHeartbeatTime time.Time `json:"lastHeartbeatTime"`
+}
+
+type TiDBStatus struct {
+	Phase       string                `json:"phase"`
+	Image       string                `json:"image"`
+	StatefulSet *StatefulSetStatus    `json:"statefulSet"`
+	Members     map[string]TiDBMember `json:"members"`
+}
+
+type TiDBMember struct {
+	Name   string `json:"name"`
+	Health bool   `json:"health"`
+	Node   string `json:"node"`
+}
+
+type TiCDCStatus struct {
+	Synced      bool                    `json:"synced"`
+	Phase       string                  `json:"phase"`
+	StatefulSet *StatefulSetStatus      `json:"statefulSet"`
+	Captures    map[string]TiCDCCapture `json:"captures"`
+}
+
+type TiCDCCapture struct {
+	PodName string `json:"podName"`
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	IsOwner bool   `json:"isOwner"`
+	Ready   bool   `json:"ready"`
+}
+
+// ClusterName returns the cluster name for the namespace, ie. merge for
+// tidb-merge.
+func ClusterName(namespace string) string {
+	return strings.TrimPrefix(namespace, "tidb-")
+}
+
+// GetTidbCluster fetches the TidbCluster named name.
+func GetTidbCluster(query Query, name string) (*TidbCluster, error) {
+	var tc TidbCluster
+	if err := query([]string{"get", "tc", name}, &tc); err != nil {
+		return nil, err
+	}
+	return &tc, nil
+}
+
+// ListTidbClusters fetches the TidbClusters in the queried namespace.
+func ListTidbClusters(query Query) ([]TidbCluster, error) {
+	var list TidbClusterList
+	if err := query([]string{"get", "tc"}, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// ID64 returns the numeric store id.
+func (s TiKVStore) ID64() (uint64, error) {
+	return strconv.ParseUint(s.ID, 10, 64)
+}
+
+// ordinal returns the ordinal of the store's pod, ie. 10 for merge-tikv-10.
+// Older operator versions don't set podName, so the pod is taken from the
+// store IP, which is the pod's peer DNS name.
+func (s TiKVStore) ordinal() (int, bool) {
+	pod := s.PodName
+	if pod == "" {
+		pod, _, _ = strings.Cut(s.IP, ".")
+	}
+
+	idx := strings.LastIndex(pod, "-")
+	if idx < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(pod[idx+1:])
+	return n, err == nil
+}
+
+// Stores returns the TiKV stores ordered by pod ordinal, falling back to the
+// store id.
+func (tc *TidbCluster) Stores() []TiKVStore {
+	stores := make([]TiKVStore, 0, len(tc.Status.TiKV.Stores))
+	for _, store := range tc.Status.TiKV.Stores {
+		stores = append(stores, store)
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		a, aOk := stores[i].ordinal()
+		b, bOk := stores[j].ordinal()
+		if aOk && bOk && a != b {
+			return a < b
+		}
+
+		aID, _ := stores[i].ID64()
+		bID, _ := stores[j].ID64()
+		return aID < bID
+	})
+	return stores
+}
+
+// StoreForPod returns the TiKV store of the pod. Older operator versions
+// don't set podName, so the store IP, which is the pod's peer DNS name, is
+// matched too.
+func (tc *TidbCluster) StoreForPod(podName string) (TiKVStore, error) {
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.PodName == podName || strings.HasPrefix(store.IP, podName+".") {
+			return store, nil
+		}
+	}
+	return TiKVStore{}, fmt.Errorf("no store found for %s", podName)
+}
+
+// Versions returns the image tag of each deployed component, ie. v7.5.1.
+func (tc *TidbCluster) Versions() map[string]string {
+	images := map[string]string{
+		"pd":      tc.Status.PD.Image,
+		"tikv":    tc.Status.TiKV.Image,
+		"tidb":    tc.Status.TiDB.Image,
+		"tiflash": tc.Status.TiFlash.Image,
+	}
+
+	versions := make(map[string]string)
+	for component, image := range images {
+		if idx := strings.LastIndex(image, ":"); idx >= 0 && !strings.Contains(image[idx:], "/") {
+			versions[component] = image[idx+1:]
+		} else if image != "" {
+			versions[component] = tc.Spec.Version
+		}
+	}
+	return versions
+}
+
+const PhaseUpgrade = "Upgrade"
+
+// ComponentStatus summarises the rollout state of a component.
+type ComponentStatus struct {
+	Name string `json:"name"`
+	// Replicas in the spec
+	Desired int `json:"desired"`
+	// Replicas of the StatefulSet
+	Current   int    `json:"current"`
+	Ready     int    `json:"ready"`
+	Updated   int    `json:"updated"`
+	Version   string `json:"version"`
+	Phase     string `json:"phase"`
+	Upgrading bool   `json:"upgrading"`
+}
+
+// Components returns the status of each component in the spec.
+func (tc *TidbCluster) Components() []ComponentStatus {
+	versions := tc.Versions()
+	components := []struct {
+		name        string
+		spec        *ComponentSpec
+		phase       string
+		statefulSet *StatefulSetStatus
+	}{
+		{"pd", tc.Spec.PD, tc.Status.PD.Phase, tc.Status.PD.StatefulSet},
+		{"tikv", tc.Spec.TiKV, tc.Status.TiKV.Phase, tc.Status.TiKV.StatefulSet},
+		{"tidb", tc.Spec.TiDB, tc.Status.TiDB.Phase, tc.Status.TiDB.StatefulSet},
+		{"tiflash", tc.Spec.TiFlash, tc.Status.TiFlash.Phase, tc.Status.TiFlash.StatefulSet},
+		{"ticdc", tc.Spec.TiCDC, tc.Status.TiCDC.Phase, tc.Status.TiCDC.StatefulSet},
+	}
+
+	statuses := make([]ComponentStatus, 0, len(components))
+	for _, c := range components {
+		if c.spec == nil {
+			continue
+		}
+
+		status := ComponentStatus{
+			Name:    c.name,
+			Desired: c.spec.Replicas,
+			Version: versions[c.name],
+			Phase:   c.phase,
+		}
+		if status.Version == "" {
+			status.Version = tc.Spec.Version
+		}
+		if c.statefulSet != nil {
+			status.Current = c.statefulSet.Replicas
+			status.Ready = c.statefulSet.ReadyReplicas
+			status.Updated = c.statefulSet.UpdatedReplicas
+			status.Upgrading = c.statefulSet.UpdateRevision != "" && c.statefulSet.CurrentRevision != c.statefulSet.UpdateRevision
+		}
+		status.Upgrading = status.Upgrading || c.phase == PhaseUpgrade
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// UnhealthyStores returns the TiKV stores which aren't Up, ordered by pod name.
+func (tc *TidbCluster) UnhealthyStores() []TiKVStore {
+	unhealthy := make([]TiKVStore, 0)
+	for _, store := range tc.Stores() {
+		if store.State != StoreStateUp {
+			unhealthy = append(unhealthy, store)
+		}
+	}
+	return unhealthy
+}
+
+// Condition returns the condition of the type, if present.
+func (tc *TidbCluster) Condition(conditionType string) (Condition, bool) {
+	for _, condition := range tc.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition, true
+		}
+	}
+	return Condition{}, false
+}
+
+// Ready returns whether the Ready condition is true.
+func (tc *TidbCluster) Ready() bool {
+	condition, ok := tc.Condition("Ready")
+	return ok && condition.Status == "True"
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureQuery returns a Query decoding the fixture file, recording the args
+// it was called with.
+func fixtureQuery(t *testing.T, path string, calls *[][]string) Query {
+	return func(args []string, v any) error {
+		*calls = append(*calls, args)
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return json.Unmarshal(data, v)
+	}
+}
+
+func loadFixture(t *testing.T) *TidbCluster {
+	var calls [][]string
+	tc, err := GetTidbCluster(fixtureQuery(t, "testdata/tidbcluster.json", &calls), "merge")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"get", "tc", "merge"}}, calls)
+	return tc
+}
+
+func TestGetTidbCluster(t *testing.T) {
+	tc := loadFixture(t)
+
+	assert.Equal(t, "merge", tc.Metadata.Name)
+	assert.Equal(t, "v7.5.1", tc.Spec.Version)
+	assert.Equal(t, 3, tc.Spec.TiKV.Replicas)
+	assert.Nil(t, tc.Spec.TiFlash)
+	assert.True(t, tc.Spec.TLSCluster.Enabled)
+
+	assert.Equal(t, "merge-pd-1", tc.Status.PD.Leader.Name)
+	assert.Len(t, tc.Status.PD.Members, 3)
+	assert.False(t, tc.Status.PD.Members["merge-pd-2"].Health)
+
+	assert.Len(t, tc.Status.TiKV.Stores, 3)
+	assert.Equal(t, StoreStateTombstone, tc.Status.TiKV.TombstoneStores["2"].State)
+	assert.Equal(t, "delete-pod", tc.Status.TiKV.EvictLeader["merge-tikv-1"].Value)
+
+	assert.Equal(t, "ip-10-0-0-2", tc.Status.TiDB.Members["merge-tidb-1"].Node)
+	assert.True(t, tc.Status.TiCDC.Captures["merge-ticdc-0"].IsOwner)
+	assert.Empty(t, tc.Status.TiFlash.Stores)
+}
+
+func TestStores(t *testing.T) {
+	tc := loadFixture(t)
+
+	stores := tc.Stores()
+	assert.Equal(t, []string{"1", "4", "10"}, []string{stores[0].ID, stores[1].ID, stores[2].ID})
+
+	id, err := stores[2].ID64()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), id)
+}
+
+func TestStoresOrdinalOrder(t *testing.T) {
+	tc := &TidbCluster{}
+	tc.Status.TiKV.Stores = map[string]TiKVStore{
+		"1": {ID: "1", PodName: "merge-tikv-10"},
+		"2": {ID: "2", PodName: "merge-tikv-2"},
+		"3": {ID: "3", IP: "merge-tikv-1.merge-tikv-peer.tidb-merge.svc"},
+		"5": {ID: "5"},
+		"4": {ID: "4"},
+	}
+
+	ids := make([]string, 0)
+	for _, store := range tc.Stores() {
+		ids = append(ids, store.ID)
+	}
+	assert.Equal(t, []string{"3", "2", "1", "4", "5"}, ids)
+}
+
+func TestStoreForPod(t *testing.T) {
+	cluster := loadFixture(t)
+
+	testCases := []struct {
+		name     string
+		pod      string
+		expected string
+		wantErr  bool
+	}{
+		// merge-tikv-1 must not match merge-tikv-10 by IP prefix
+		{name: "By pod name", pod: "merge-tikv-1", expected: "4"},
+		{name: "By IP without pod name", pod: "merge-tikv-10", expected: "10"},
+		{name: "Tombstone stores are ignored", pod: "merge-tikv-2", wantErr: true},
+		{name: "Missing", pod: "merge-tikv-9", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := cluster.StoreForPod(tc.pod)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, store.ID)
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	tc := loadFixture(t)
+	assert.Equal(t, map[string]string{
+		"pd":   "v7.5.1",
+		"tikv": "v7.5.1",
+		"tidb": "v7.5.1",
+	}, tc.Versions())
+}
+
+func TestReady(t *testing.T) {
+	tc := loadFixture(t)
+	assert.True(t, tc.Ready())
+
+	tc.Status.Conditions[0].Status = "False"
+	assert.False(t, tc.Ready())
+
+	tc.Status.Conditions = nil
+	assert.False(t, tc.Ready())
+}
+
+func TestListTidbClusters(t *testing.T) {
+	var calls [][]string
+	query := func(args []string, v any) error {
+		calls = append(calls, args)
+		data, err := os.ReadFile("testdata/tidbcluster.json")
+		assert.NoError(t, err)
+		return json.Unmarshal([]byte(`{"items": [`+string(data)+`]}`), v)
+	}
+
+	clusters, err := ListTidbClusters(query)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"get", "tc"}}, calls)
+	assert.Len(t, clusters, 1)
+	assert.Equal(t, "merge", clusters[0].Metadata.Name)
+}
+
+func TestComponents(t *testing.T) {
+	tc := loadFixture(t)
+
+	assert.Equal(t, []ComponentStatus{
+		{Name: "pd", Desired: 3, Current: 3, Ready: 3, Updated: 3, Version: "v7.5.1", Phase: "Normal"},
+		{Name: "tikv", Desired: 3, Current: 3, Ready: 2, Updated: 1, Version: "v7.5.1", Phase: "Upgrade", Upgrading: true},
+		{Name: "tidb", Desired: 2, Current: 2, Ready: 2, Updated: 2, Version: "v7.5.1", Phase: "Normal"},
+		{Name: "ticdc", Desired: 2, Current: 2, Ready: 2, Updated: 2, Version: "v7.5.1", Phase: "Normal"},
+	}, tc.Components())
+}
+
+func TestUnhealthyStores(t *testing.T) {
+	tc := loadFixture(t)
+
+	unhealthy := tc.UnhealthyStores()
+	assert.Len(t, unhealthy, 1)
+	assert.Equal(t, "10", unhealthy[0].ID)
+	assert.Equal(t, StoreStateDown, unhealthy[0].State)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/fatih/color"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
 )
 
 func colorDebugPrintfln(context string, format string, args ...interface{}) {
@@ -20,6 +21,15 @@ func colorDebugPrintfln(context string, format string, args ...interface{}) {
 	}
 }
 
+// colorDebugStickyUsage notes when the context or namespace came from the
+// sticky state set by `mdcli k8s use`, given the notes from ParseContext and
+// ParseNamespace.
+func colorDebugStickyUsage(context string, notes ...string) {
+	if note := mdk8s.StickyUsage(notes...); note != "" {
+		colorDebugPrintfln(context, "Using %s", note)
+	}
+}
+
 func debugPrintfln(format string, args ...interface{}) {
 	format = fmt.Sprintf("%s\n", format)
 	fmt.Fprintf(os.Stderr, format, args...)
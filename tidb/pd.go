@@ -0,0 +1,65 @@
+package tidb
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
+)
+
+const (
+	pdClientPort   = 2379
+	tidbStatusPort = 10080
+)
+
+// forwardClusterService port-forwards to the port of the component's service,
+// ie. pd, returning the base URL of the forward. If the cluster has TLS
+// enabled, the returned TLS config authenticates with the cluster client
+// secret.
+func forwardClusterService(builder *mdk8s.KubeBuilder, context, namespace, component string, port int, debug bool) (string, *tls.Config, error) {
+	clusterName := model.ClusterName(namespace)
+	tc, err := model.GetTidbCluster(kubectlQuery(builder, context, namespace, false, debug), clusterName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	service := fmt.Sprintf("svc/%s-%s", clusterName, component)
+	if debug {
+		colorDebugPrintfln(context, "Forwarding %s:%d via managed port-forward", service, port)
+	}
+	pf, err := builder.EnsurePortForward(mdk8s.PortForwardOptions{
+		Context:    context,
+		Namespace:  namespace,
+		Target:     service,
+		RemotePort: port,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("port-forward failed: %w", err)
+	}
+
+	if tc.Spec.TLSCluster == nil || !tc.Spec.TLSCluster.Enabled {
+		return "http://" + pf.Addr(), nil, nil
+	}
+
+	secret, err := builder.GetSecret(context, namespace, clusterName+"-cluster-client-secret", false)
+	if err != nil {
+		return "", nil, err
+	}
+	tlsConfig, err := pdapi.TLSConfig(secret["ca.crt"], secret["tls.crt"], secret["tls.key"], clusterName+"-"+component)
+	if err != nil {
+		return "", nil, err
+	}
+	return "https://" + pf.Addr(), tlsConfig, nil
+}
+
+// newPdClient returns a PD API client for the cluster in namespace, connected
+// through a managed port-forward to the PD service.
+func newPdClient(builder *mdk8s.KubeBuilder, context, namespace string, debug bool) (*pdapi.Client, error) {
+	baseURL, tlsConfig, err := forwardClusterService(builder, context, namespace, "pd", pdClientPort, debug)
+	if err != nil {
+		return nil, err
+	}
+	return pdapi.NewClient(baseURL, tlsConfig), nil
+}
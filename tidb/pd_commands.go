@@ -1,10 +1,22 @@
 package tidb
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,6 +26,341 @@ func BasePdCommand() *cli.Command {
 		Usage: `Commands for handling PD on K8s`,
 		Subcommands: []*cli.Command{
 			pdTsoCommand(),
+			pdSchedulerCommand(),
+			pdConfigCommand(),
+		},
+	}
+}
+
+var pdYesFlag = &cli.BoolFlag{
+	Name:    "yes",
+	Aliases: []string{"y"},
+	Value:   false,
+	Usage:   "Automatic yes to confirmation prompts",
+}
+
+// pdTarget is the cluster a pd command runs against.
+type pdTarget struct {
+	context   string
+	namespace string
+	builder   *mdk8s.KubeBuilder
+	query     model.Query
+	client    *pdapi.Client
+}
+
+// resolvePdTarget resolves the cluster from the k8s flags and connects to its
+// PD.
+func resolvePdTarget(cCtx *cli.Context) (*pdTarget, error) {
+	strict := cCtx.Bool("strict")
+	context := cCtx.String("context")
+	namespace := cCtx.String("namespace")
+	interactive := cCtx.Bool("interactive")
+	debug := cCtx.Bool("debug") && !mdexec.IsPipe()
+
+	context = inferContextFromNamespace(context, namespace)
+
+	var err error
+	var contextNote, namespaceNote string
+	context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+	if err != nil {
+		return nil, err
+	}
+
+	context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+	if err != nil {
+		return nil, err
+	}
+
+	if debug {
+		colorDebugStickyUsage(context, contextNote, namespaceNote)
+	}
+
+	builder := NewTidbKubeBuilder()
+	client, err := newPdClient(&builder, context, namespace, debug)
+	if err != nil {
+		return nil, err
+	}
+	return &pdTarget{
+		context:   context,
+		namespace: namespace,
+		builder:   &builder,
+		query:     kubectlQuery(&builder, context, namespace, false, debug),
+		client:    client,
+	}, nil
+}
+
+// confirm prints the change in the colour of the environment and asks for
+// confirmation, unless confirmed.
+func (t *pdTarget) confirm(change string, confirmed bool) bool {
+	colorDebugPrintfln(t.context, "%s in %s/%s", change, t.context, t.namespace)
+	if confirmed {
+		return true
+	}
+	return mdexec.GetConfirmation("Do you want to apply the above change?")
+}
+
+// log records the applied change in the PD change log, warning if it can't.
+func (t *pdTarget) log(action string, changes []configChange) {
+	err := logPdChange(pdChange{
+		Context:   t.context,
+		Namespace: t.namespace,
+		Action:    action,
+		Changes:   changes,
+	})
+	if err != nil {
+		debugPrintfln("Failed to write PD change log: %v", err)
+	}
+}
+
+// applyPdChange resolves the target, confirms the change and applies it,
+// logging it if it succeeds.
+func applyPdChange(cCtx *cli.Context, action string, apply func(*pdTarget) error) error {
+	target, err := resolvePdTarget(cCtx)
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+
+	if !target.confirm(action, cCtx.Bool("yes")) {
+		return cli.Exit("Command canceled by user", 1)
+	}
+	if err := apply(target); err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+	target.log(action, nil)
+	return nil
+}
+
+func pdSchedulerCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "scheduler",
+		Aliases: []string{"schedulers", "sched"},
+		Usage:   "Manage PD schedulers",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List the PD schedulers",
+				Flags:   mdk8s.BaseK8sFlags,
+				Action: func(cCtx *cli.Context) error {
+					target, err := resolvePdTarget(cCtx)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					schedulers, err := target.client.Schedulers()
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					paused, err := target.client.PausedSchedulers()
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					sort.Strings(schedulers)
+					for _, scheduler := range schedulers {
+						if slices.Contains(paused, scheduler) {
+							fmt.Printf("%s (paused)\n", scheduler)
+						} else {
+							fmt.Println(scheduler)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "add",
+				Usage:     "Add a scheduler, ie. evict-leader-scheduler with the store id or tikv pod to evict from",
+				ArgsUsage: "<scheduler> [store]",
+				Flags:     append(mdk8s.BaseK8sFlags, pdYesFlag),
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() < 1 || cCtx.NArg() > 2 {
+						return cli.Exit("a scheduler and optionally a store must be provided", 1)
+					}
+					name := cCtx.Args().Get(0)
+					store := cCtx.Args().Get(1)
+
+					action := "Add scheduler " + name
+					if store != "" {
+						action += " for store " + store
+					}
+					return applyPdChange(cCtx, action, func(target *pdTarget) error {
+						args := map[string]any{}
+						if store != "" {
+							storeId, err := strconv.Atoi(store)
+							if err != nil {
+								clusterName := model.ClusterName(target.namespace)
+								storeId, err = findTikvStoreId(target.query, clusterName, tikvPodName(clusterName, store))
+								if err != nil {
+									return err
+								}
+							}
+							args["store_id"] = storeId
+						}
+						return target.client.AddScheduler(name, args)
+					})
+				},
+			},
+			{
+				Name:      "rm",
+				Aliases:   []string{"remove", "delete"},
+				Usage:     "Remove a scheduler, ie. evict-leader-scheduler-4",
+				ArgsUsage: "<scheduler>",
+				Flags:     append(mdk8s.BaseK8sFlags, pdYesFlag),
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 1 {
+						return cli.Exit("a scheduler must be provided", 1)
+					}
+					name := cCtx.Args().First()
+					return applyPdChange(cCtx, "Remove scheduler "+name, func(target *pdTarget) error {
+						return target.client.RemoveScheduler(name)
+					})
+				},
+			},
+			{
+				Name:      "pause",
+				Usage:     "Pause a scheduler",
+				ArgsUsage: "<scheduler>",
+				Flags: append(mdk8s.BaseK8sFlags, pdYesFlag, &cli.DurationFlag{
+					Name:  "duration",
+					Value: time.Hour,
+					Usage: "`DURATION` to pause for, after which PD resumes the scheduler",
+				}),
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 1 {
+						return cli.Exit("a scheduler must be provided", 1)
+					}
+					name := cCtx.Args().First()
+					duration := cCtx.Duration("duration")
+					if duration < time.Second {
+						return cli.Exit("--duration must be at least 1s", 1)
+					}
+					return applyPdChange(cCtx, fmt.Sprintf("Pause scheduler %s for %s", name, duration), func(target *pdTarget) error {
+						return target.client.PauseScheduler(name, duration)
+					})
+				},
+			},
+			{
+				Name:      "resume",
+				Usage:     "Resume a paused scheduler",
+				ArgsUsage: "<scheduler>",
+				Flags:     append(mdk8s.BaseK8sFlags, pdYesFlag),
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 1 {
+						return cli.Exit("a scheduler must be provided", 1)
+					}
+					name := cCtx.Args().First()
+					return applyPdChange(cCtx, "Resume scheduler "+name, func(target *pdTarget) error {
+						return target.client.PauseScheduler(name, 0)
+					})
+				},
+			},
+		},
+	}
+}
+
+func pdConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Show and change the PD config",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "get",
+				Usage:     "Print the PD config, or a section or item of it, ie. schedule.leader-schedule-limit",
+				ArgsUsage: "[key]",
+				Flags:     mdk8s.BaseK8sFlags,
+				Action: func(cCtx *cli.Context) error {
+					target, err := resolvePdTarget(cCtx)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					config, err := target.client.Config()
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					var value any = map[string]any(config)
+					if key := cCtx.Args().First(); key != "" {
+						var ok bool
+						value, ok = lookupConfig(config, key)
+						if !ok {
+							return cli.Exit(fmt.Sprintf("unknown config key '%s'", key), 1)
+						}
+					}
+
+					out, err := json.MarshalIndent(value, "", "  ")
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					fmt.Println(string(out))
+					return nil
+				},
+			},
+			{
+				Name:      "set",
+				Usage:     "Set a PD config item, showing the diff and asking for confirmation first",
+				ArgsUsage: "<key> <value>",
+				Flags:     append(mdk8s.BaseK8sFlags, pdYesFlag),
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 2 {
+						return cli.Exit("a config key and value must be provided", 1)
+					}
+					key, value := cCtx.Args().Get(0), cCtx.Args().Get(1)
+
+					target, err := resolvePdTarget(cCtx)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					config, err := target.client.Config()
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					before := flattenConfig(config)
+					current, ok := before[key]
+					if !ok {
+						return cli.Exit(fmt.Sprintf("unknown config key '%s'", key), 1)
+					}
+					parsed, err := parseConfigValue(current, value)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("invalid value for %s: %v", key, err), 1)
+					}
+
+					expected := maps.Clone(before)
+					expected[key] = parsed
+					changes := diffConfig(before, expected)
+					if len(changes) == 0 {
+						fmt.Printf("%s is already %s\n", key, formatConfigValue(current))
+						return nil
+					}
+
+					printConfigDiff(os.Stdout, changes)
+					if !target.confirm("Set "+key, cCtx.Bool("yes")) {
+						return cli.Exit("Command canceled by user", 1)
+					}
+					if err := target.client.SetConfig(map[string]any{key: parsed}); err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+
+					config, err = target.client.Config()
+					if err != nil {
+						target.log("Set "+key, changes)
+						return cli.Exit(fmt.Sprintf("set %s but failed to verify it: %v", key, err), 1)
+					}
+					applied := diffConfig(before, flattenConfig(config))
+					target.log("Set "+key, applied)
+					if !reflect.DeepEqual(applied, changes) {
+						fmt.Println("PD applied:")
+						printConfigDiff(os.Stdout, applied)
+					}
+					return nil
+				},
+			},
 		},
 	}
 }
@@ -21,36 +368,66 @@ func BasePdCommand() *cli.Command {
 func pdTsoCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "tso",
-		Usage:     "Perform TSO/timestamp conversion. Provide either a TSO (int) or a timestamp (RFC3339 string).",
-		ArgsUsage: "<tso_or_timestamp>",
+		Usage:     "Convert between TSOs and times. Reads one value per line from stdin if none is given",
+		ArgsUsage: "[tso|unix-seconds|unix-millis|time|now|now-15m|15m-ago]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "utc",
+				Value: false,
+				Usage: "Print times in UTC instead of the local zone",
+			},
+		},
 		Action: func(cCtx *cli.Context) error {
-			if cCtx.NArg() != 1 {
-				return cli.Exit("exactly one argument (tso or timestamp) must be provided", 1)
+			if cCtx.NArg() > 1 {
+				return cli.Exit("at most one argument (tso or time) must be provided", 1)
 			}
-			input := cCtx.Args().Get(0)
-
-			// Try parsing as TSO (integer)
-			tso, err := strconv.ParseInt(input, 10, 64)
-			if err == nil {
-				// Successfully parsed as TSO
-				t := time.Unix(int64((tso/1000)>>18), 0).UTC()
-				ts := t.Format(time.RFC3339)
-				fmt.Println(ts)
-				return nil
+
+			loc := time.Local
+			if cCtx.Bool("utc") {
+				loc = time.UTC
 			}
+			now := time.Now()
+			w := cCtx.App.Writer
 
-			// Try parsing as timestamp (RFC3339 string)
-			t, err := time.Parse(time.RFC3339, input)
-			if err == nil {
-				// Successfully parsed as timestamp
-				tsUnix := t.Unix()
-				tsoResult := (tsUnix << 18) * 1000
-				fmt.Println(tsoResult)
+			if input := cCtx.Args().First(); input != "" && input != "-" {
+				tso, err := parseTSOInput(input, now)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+
+				info := newTSOInfo(tso, loc)
+				fmt.Fprintf(w, "TSO:      %d\n", info.TSO)
+				fmt.Fprintf(w, "Physical: %s\n", info.Physical.Format(tsoTimeLayout))
+				fmt.Fprintf(w, "Logical:  %d\n", info.Logical)
+				fmt.Fprintf(w, "Age:      %s\n", tsoAge(info.Physical, now))
 				return nil
-			} else {
-				// If both parsing attempts fail, return an error.
-				return cli.Exit(fmt.Sprintf("input '%s' is not a valid TSO (integer) or timestamp (RFC3339): %s", input, err), 1)
 			}
+
+			failed := false
+			scanner := bufio.NewScanner(cCtx.App.Reader)
+			for scanner.Scan() {
+				input := strings.TrimSpace(scanner.Text())
+				if input == "" {
+					continue
+				}
+
+				tso, err := parseTSOInput(input, now)
+				if err != nil {
+					fmt.Fprintln(cCtx.App.ErrWriter, err)
+					failed = true
+					continue
+				}
+
+				info := newTSOInfo(tso, loc)
+				fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", input, info.TSO, info.Physical.Format(tsoTimeLayout), info.Logical, tsoAge(info.Physical, now))
+			}
+			if err := scanner.Err(); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if failed {
+				return cli.Exit("", 1)
+			}
+			return nil
 		},
 	}
 }
@@ -0,0 +1,165 @@
+package tidb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/michaelmdeng/mdcli/internal/state"
+)
+
+// flattenConfig flattens the nested config into its items keyed by dotted
+// name, ie. schedule.leader-schedule-limit.
+func flattenConfig(config map[string]any) map[string]any {
+	items := make(map[string]any)
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+				flatten(prefix+k+".", nested)
+			} else {
+				items[prefix+k] = v
+			}
+		}
+	}
+	flatten("", config)
+	return items
+}
+
+// lookupConfig returns the section or item of the config at the dotted key.
+func lookupConfig(config map[string]any, key string) (any, bool) {
+	if v, ok := config[key]; ok {
+		return v, true
+	}
+	for section, v := range config {
+		nested, ok := v.(map[string]any)
+		if ok && strings.HasPrefix(key, section+".") {
+			if v, ok := lookupConfig(nested, strings.TrimPrefix(key, section+".")); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseConfigValue parses value as the type of the current value of the item,
+// so numbers and bools aren't sent to PD as strings.
+func parseConfigValue(current any, value string) (any, error) {
+	switch current.(type) {
+	case float64:
+		return strconv.ParseFloat(value, 64)
+	case bool:
+		return strconv.ParseBool(value)
+	case string:
+		return value, nil
+	default:
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("value must be JSON: %w", err)
+		}
+		return v, nil
+	}
+}
+
+// configChange is a changed config item.
+type configChange struct {
+	Key    string `json:"key"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// diffConfig returns the items which differ between the flattened configs, in
+// key order.
+func diffConfig(before, after map[string]any) []configChange {
+	keys := make(map[string]struct{})
+	for k := range before {
+		keys[k] = struct{}{}
+	}
+	for k := range after {
+		keys[k] = struct{}{}
+	}
+
+	changes := make([]configChange, 0)
+	for k := range keys {
+		if !reflect.DeepEqual(before[k], after[k]) {
+			changes = append(changes, configChange{Key: k, Before: before[k], After: after[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+func formatConfigValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// printConfigDiff writes the changes as removed and added lines.
+func printConfigDiff(w io.Writer, changes []configChange) {
+	for _, change := range changes {
+		fmt.Fprintln(w, color.RedString("- %s: %s", change.Key, formatConfigValue(change.Before)))
+		fmt.Fprintln(w, color.GreenString("+ %s: %s", change.Key, formatConfigValue(change.After)))
+	}
+}
+
+// pdChange is an entry in the PD change log.
+type pdChange struct {
+	Time      time.Time      `json:"time"`
+	User      string         `json:"user"`
+	Context   string         `json:"context"`
+	Namespace string         `json:"namespace"`
+	Action    string         `json:"action"`
+	Changes   []configChange `json:"changes,omitempty"`
+}
+
+func pdChangeLogPath() (string, error) {
+	return state.Path("tidb", "pd-changes.log")
+}
+
+// logPdChange appends the change to the PD change log as a JSON line.
+func logPdChange(change pdChange) error {
+	path, err := pdChangeLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if change.Time.IsZero() {
+		change.Time = time.Now()
+	}
+	if change.User == "" {
+		change.User = os.Getenv("USER")
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,106 @@
+package tidb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pdConfigFixture = `{
+  "replication": {"max-replicas": 3, "location-labels": "zone,host"},
+  "schedule": {
+    "leader-schedule-limit": 4,
+    "enable-cross-table-merge": "true",
+    "schedulers-v2": [{"type": "balance-leader"}],
+    "store-limit": {"1": {"add-peer": 15}}
+  },
+  "pd-server": {"key-type": "table"}
+}`
+
+func loadPdConfigFixture(t *testing.T) map[string]any {
+	var config map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(pdConfigFixture), &config))
+	return config
+}
+
+func TestFlattenConfig(t *testing.T) {
+	items := flattenConfig(loadPdConfigFixture(t))
+
+	assert.Equal(t, float64(4), items["schedule.leader-schedule-limit"])
+	assert.Equal(t, float64(15), items["schedule.store-limit.1.add-peer"])
+	assert.Equal(t, []any{map[string]any{"type": "balance-leader"}}, items["schedule.schedulers-v2"])
+	assert.Equal(t, "table", items["pd-server.key-type"])
+	assert.NotContains(t, items, "schedule")
+}
+
+func TestLookupConfig(t *testing.T) {
+	config := loadPdConfigFixture(t)
+
+	value, ok := lookupConfig(config, "schedule.leader-schedule-limit")
+	assert.True(t, ok)
+	assert.Equal(t, float64(4), value)
+
+	value, ok = lookupConfig(config, "replication")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]any{"max-replicas": float64(3), "location-labels": "zone,host"}, value)
+
+	_, ok = lookupConfig(config, "schedule.missing")
+	assert.False(t, ok)
+}
+
+func TestParseConfigValue(t *testing.T) {
+	value, err := parseConfigValue(float64(4), "8")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(8), value)
+
+	value, err = parseConfigValue(false, "true")
+	assert.NoError(t, err)
+	assert.Equal(t, true, value)
+
+	value, err = parseConfigValue("true", "false")
+	assert.NoError(t, err)
+	assert.Equal(t, "false", value)
+
+	value, err = parseConfigValue([]any{}, `["a"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"a"}, value)
+
+	_, err = parseConfigValue(float64(4), "four")
+	assert.Error(t, err)
+}
+
+func TestDiffConfig(t *testing.T) {
+	before := map[string]any{"schedule.leader-schedule-limit": float64(4), "schedule.region-schedule-limit": float64(2048)}
+	after := map[string]any{"schedule.leader-schedule-limit": float64(8), "schedule.region-schedule-limit": float64(2048), "schedule.new": true}
+
+	assert.Equal(t, []configChange{
+		{Key: "schedule.leader-schedule-limit", Before: float64(4), After: float64(8)},
+		{Key: "schedule.new", After: true},
+	}, diffConfig(before, after))
+	assert.Empty(t, diffConfig(before, before))
+}
+
+func TestLogPdChange(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	assert.NoError(t, logPdChange(pdChange{Context: "m-tidb-test", Namespace: "tidb-merge", Action: "Remove scheduler evict-leader-scheduler-4"}))
+	assert.NoError(t, logPdChange(pdChange{Context: "m-tidb-test", Namespace: "tidb-merge", Action: "Set schedule.leader-schedule-limit"}))
+
+	path, err := pdChangeLogPath()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(os.Getenv("XDG_STATE_HOME"), "mdcli", "tidb", "pd-changes.log"), path)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+
+	var change pdChange
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &change))
+	assert.Equal(t, "Set schedule.leader-schedule-limit", change.Action)
+	assert.False(t, change.Time.IsZero())
+}
@@ -0,0 +1,279 @@
+// Package pdapi is a client for the PD HTTP API.
+package pdapi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiPrefix      = "/pd/api/v1"
+	defaultTimeout = 30 * time.Second
+)
+
+// Client queries the PD HTTP API at a base URL, ie. https://127.0.0.1:2379.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the PD at baseURL, using tlsConfig if not
+// nil.
+func NewClient(baseURL string, tlsConfig *tls.Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+		},
+	}
+}
+
+// TLSConfig returns the client TLS config from the PEM encoded CA, cert and
+// key, ie. from the cluster client secret. serverName is verified against the
+// PD certificate, as the address of a port-forward won't match it.
+func TLSConfig(ca, cert, key []byte, serverName string) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to parse CA certificate")
+	}
+
+	keyPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{keyPair},
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// APIError is a non-2xx response from PD.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// do sends body, if not nil, as JSON and decodes the response into v, if not
+// nil.
+func (c *Client) do(method, path string, body any, v any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Stores returns the stores, excluding tombstones.
+func (c *Client) Stores() (*StoresInfo, error) {
+	var stores StoresInfo
+	if err := c.do(http.MethodGet, "/stores", nil, &stores); err != nil {
+		return nil, err
+	}
+	return &stores, nil
+}
+
+// Store returns the store by id.
+func (c *Client) Store(id uint64) (*StoreInfo, error) {
+	var store StoreInfo
+	if err := c.do(http.MethodGet, "/store/"+strconv.FormatUint(id, 10), nil, &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// Regions returns every region, which may be large.
+func (c *Client) Regions() (*RegionsInfo, error) {
+	var regions RegionsInfo
+	if err := c.do(http.MethodGet, "/regions", nil, &regions); err != nil {
+		return nil, err
+	}
+	return &regions, nil
+}
+
+// Region returns the region by id.
+func (c *Client) Region(id uint64) (*Region, error) {
+	var region Region
+	if err := c.do(http.MethodGet, "/region/id/"+strconv.FormatUint(id, 10), nil, &region); err != nil {
+		return nil, err
+	}
+	if region.ID == 0 {
+		return nil, fmt.Errorf("region %d not found", id)
+	}
+	return &region, nil
+}
+
+// RegionByKey returns the region containing the key. PD indexes regions by
+// memcomparable-encoded keys, so key must be encoded, ie. with
+// keycodec.EncodeBytes, not a raw TiDB key.
+func (c *Client) RegionByKey(key []byte) (*Region, error) {
+	var region Region
+	if err := c.do(http.MethodGet, "/region/key/"+url.PathEscape(string(key)), nil, &region); err != nil {
+		return nil, err
+	}
+	if region.ID == 0 {
+		return nil, fmt.Errorf("no region contains key %X", key)
+	}
+	return &region, nil
+}
+
+// RegionsByKey returns up to limit regions from the one containing the start
+// key, stopping before the end key if not empty. Like RegionByKey, both keys
+// must be memcomparable-encoded.
+func (c *Client) RegionsByKey(start, end []byte, limit int) (*RegionsInfo, error) {
+	query := url.Values{}
+	query.Set("key", string(start))
+	if len(end) > 0 {
+		query.Set("end_key", string(end))
+	}
+	query.Set("limit", strconv.Itoa(limit))
+
+	var regions RegionsInfo
+	if err := c.do(http.MethodGet, "/regions/key?"+query.Encode(), nil, &regions); err != nil {
+		return nil, err
+	}
+	return &regions, nil
+}
+
+// HotRegions returns the hot regions of each store, for kind read or write.
+func (c *Client) HotRegions(kind string) (*HotRegions, error) {
+	var hot HotRegions
+	if err := c.do(http.MethodGet, "/hotspot/regions/"+url.PathEscape(kind), nil, &hot); err != nil {
+		return nil, err
+	}
+	return &hot, nil
+}
+
+// Members returns the PD members and leader.
+func (c *Client) Members() (*Members, error) {
+	var members Members
+	if err := c.do(http.MethodGet, "/members", nil, &members); err != nil {
+		return nil, err
+	}
+	return &members, nil
+}
+
+// Config returns the PD config.
+func (c *Client) Config() (Config, error) {
+	var config Config
+	if err := c.do(http.MethodGet, "/config", nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// SetConfig updates the config items, keyed by their name, ie.
+// schedule.leader-schedule-limit.
+func (c *Client) SetConfig(items map[string]any) error {
+	return c.do(http.MethodPost, "/config", items, nil)
+}
+
+// Schedulers returns the names of the running schedulers.
+func (c *Client) Schedulers() ([]string, error) {
+	var schedulers []string
+	if err := c.do(http.MethodGet, "/schedulers", nil, &schedulers); err != nil {
+		return nil, err
+	}
+	return schedulers, nil
+}
+
+// PausedSchedulers returns the names of the paused schedulers.
+func (c *Client) PausedSchedulers() ([]string, error) {
+	var schedulers []string
+	if err := c.do(http.MethodGet, "/schedulers?status=paused", nil, &schedulers); err != nil {
+		return nil, err
+	}
+	return schedulers, nil
+}
+
+// AddScheduler adds the scheduler with its args, ie. store_id for
+// evict-leader-scheduler.
+func (c *Client) AddScheduler(name string, args map[string]any) error {
+	body := map[string]any{"name": name}
+	for k, v := range args {
+		body[k] = v
+	}
+	return c.do(http.MethodPost, "/schedulers", body, nil)
+}
+
+// RemoveScheduler removes the scheduler.
+func (c *Client) RemoveScheduler(name string) error {
+	return c.do(http.MethodDelete, "/schedulers/"+url.PathEscape(name), nil, nil)
+}
+
+// PauseScheduler pauses the scheduler for delay, or resumes it if delay is 0.
+func (c *Client) PauseScheduler(name string, delay time.Duration) error {
+	body := map[string]any{"delay": int64(delay.Seconds())}
+	return c.do(http.MethodPost, "/schedulers/"+url.PathEscape(name), body, nil)
+}
+
+// GCSafePoint returns the GC safepoint and the service safepoints holding it
+// back.
+func (c *Client) GCSafePoint() (*GCSafePoint, error) {
+	var safePoint GCSafePoint
+	if err := c.do(http.MethodGet, "/gc/safepoint", nil, &safePoint); err != nil {
+		return nil, err
+	}
+	return &safePoint, nil
+}
@@ -0,0 +1,218 @@
+package pdapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	Method string
+	Path   string
+	Body   map[string]any
+}
+
+// newTestPD returns a client for a PD stand-in serving the responses by path,
+// recording the requests it receives.
+func newTestPD(t *testing.T, responses map[string]string) (*Client, *[]request) {
+	requests := make([]request, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{Method: r.Method, Path: r.URL.EscapedPath()}
+		if r.URL.RawQuery != "" {
+			req.Path += "?" + r.URL.RawQuery
+		}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			assert.NoError(t, json.Unmarshal(data, &req.Body))
+		}
+		requests = append(requests, req)
+
+		response, ok := responses[r.Method+" "+req.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL+"/", nil), &requests
+}
+
+func TestStores(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/stores": `{"count": 1, "stores": [{
+			"store": {"id": 4, "address": "merge-tikv-1.merge-tikv-peer.tidb-merge.svc:20160", "state_name": "Up", "labels": [{"key": "zone", "value": "us-east-1a"}]},
+			"status": {"leader_count": 120, "region_count": 300, "capacity": "1TiB"}
+		}]}`,
+	})
+
+	stores, err := client.Stores()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stores.Count)
+	assert.Equal(t, uint64(4), stores.Stores[0].Store.ID)
+	assert.Equal(t, "Up", stores.Stores[0].Store.StateName)
+	assert.Equal(t, []StoreLabel{{Key: "zone", Value: "us-east-1a"}}, stores.Stores[0].Store.Labels)
+	assert.Equal(t, 120, stores.Stores[0].Status.LeaderCount)
+	assert.Equal(t, 300, stores.Stores[0].Status.RegionCount)
+}
+
+func TestRegion(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/region/id/10": `{
+			"id": 10, "start_key": "7480000000000000FF2D5F720000000000FA", "end_key": "",
+			"epoch": {"conf_ver": 5, "version": 20},
+			"peers": [{"id": 11, "store_id": 1}, {"id": 12, "store_id": 4}],
+			"leader": {"id": 12, "store_id": 4},
+			"approximate_size": 96
+		}`,
+		"GET /pd/api/v1/region/id/99":    `{}`,
+		"GET /pd/api/v1/region/key/t%80": `{"id": 10}`,
+	})
+
+	region, err := client.Region(10)
+	assert.NoError(t, err)
+	assert.Equal(t, "7480000000000000FF2D5F720000000000FA", region.StartKey)
+	assert.Len(t, region.Peers, 2)
+	assert.Equal(t, uint64(4), region.Leader.StoreID)
+	assert.Equal(t, RegionEpoch{ConfVer: 5, Version: 20}, region.RegionEpoch)
+
+	_, err = client.Region(99)
+	assert.EqualError(t, err, "region 99 not found")
+
+	region, err = client.RegionByKey([]byte{'t', 0x80})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), region.ID)
+}
+
+func TestRegionsByKey(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/regions/key?end_key=t%81&key=t%80&limit=2": `{"count": 2, "regions": [{"id": 10}, {"id": 14}]}`,
+		"GET /pd/api/v1/regions/key?key=t%80&limit=16":             `{"count": 1, "regions": [{"id": 10}]}`,
+	})
+
+	regions, err := client.RegionsByKey([]byte{'t', 0x80}, []byte{'t', 0x81}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, regions.Count)
+	assert.Equal(t, uint64(14), regions.Regions[1].ID)
+
+	regions, err = client.RegionsByKey([]byte{'t', 0x80}, nil, 16)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, regions.Count)
+}
+
+func TestHotRegions(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/hotspot/regions/read": `{
+			"as_leader": {"4": {"total_flow_bytes": 2048, "regions_count": 1, "statistics": [
+				{"store_id": 4, "region_id": 10, "is_leader": true, "hot_degree": 30, "flow_bytes": 2048, "flow_keys": 16}
+			]}},
+			"as_peer": {}
+		}`,
+	})
+
+	hot, err := client.HotRegions("read")
+	assert.NoError(t, err)
+	assert.Equal(t, []HotPeerStat{
+		{StoreID: 4, RegionID: 10, IsLeader: true, HotDegree: 30, ByteRate: 2048, KeyRate: 16},
+	}, hot.AsLeader[4].Stats)
+	assert.Empty(t, hot.AsPeer)
+
+	_, err = client.HotRegions("scan")
+	assert.Error(t, err)
+}
+
+func TestMembers(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/members": `{
+			"members": [{"name": "merge-pd-0", "member_id": 1}, {"name": "merge-pd-1", "member_id": 2}],
+			"leader": {"name": "merge-pd-1", "member_id": 2},
+			"etcd_leader": {"name": "merge-pd-1", "member_id": 2}
+		}`,
+	})
+
+	members, err := client.Members()
+	assert.NoError(t, err)
+	assert.Len(t, members.Members, 2)
+	assert.Equal(t, "merge-pd-1", members.Leader.Name)
+}
+
+func TestConfig(t *testing.T) {
+	client, requests := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/config":  `{"schedule": {"leader-schedule-limit": 4}, "replication": {"max-replicas": 3}}`,
+		"POST /pd/api/v1/config": ``,
+	})
+
+	config, err := client.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"leader-schedule-limit": float64(4)}, config["schedule"])
+
+	assert.NoError(t, client.SetConfig(map[string]any{"schedule.leader-schedule-limit": 8}))
+	assert.Equal(t, request{
+		Method: "POST",
+		Path:   "/pd/api/v1/config",
+		Body:   map[string]any{"schedule.leader-schedule-limit": float64(8)},
+	}, (*requests)[1])
+}
+
+func TestSchedulers(t *testing.T) {
+	client, requests := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/schedulers":                             `["balance-leader-scheduler", "evict-leader-scheduler-4"]`,
+		"GET /pd/api/v1/schedulers?status=paused":               `["balance-leader-scheduler"]`,
+		"POST /pd/api/v1/schedulers":                            ``,
+		"DELETE /pd/api/v1/schedulers/evict-leader-scheduler-4": ``,
+		"POST /pd/api/v1/schedulers/balance-leader-scheduler":   ``,
+	})
+
+	schedulers, err := client.Schedulers()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"balance-leader-scheduler", "evict-leader-scheduler-4"}, schedulers)
+
+	paused, err := client.PausedSchedulers()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"balance-leader-scheduler"}, paused)
+
+	assert.NoError(t, client.AddScheduler("evict-leader-scheduler", map[string]any{"store_id": 4}))
+	assert.NoError(t, client.RemoveScheduler("evict-leader-scheduler-4"))
+	assert.NoError(t, client.PauseScheduler("balance-leader-scheduler", time.Hour))
+	assert.Equal(t, []request{
+		{Method: "GET", Path: "/pd/api/v1/schedulers"},
+		{Method: "GET", Path: "/pd/api/v1/schedulers?status=paused"},
+		{Method: "POST", Path: "/pd/api/v1/schedulers", Body: map[string]any{"name": "evict-leader-scheduler", "store_id": float64(4)}},
+		{Method: "DELETE", Path: "/pd/api/v1/schedulers/evict-leader-scheduler-4"},
+		{Method: "POST", Path: "/pd/api/v1/schedulers/balance-leader-scheduler", Body: map[string]any{"delay": float64(3600)}},
+	}, *requests)
+}
+
+func TestGCSafePoint(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{
+		"GET /pd/api/v1/gc/safepoint": `{
+			"service_gc_safe_points": [{"service_id": "gc_worker", "expired_at": 9223372036854775807, "safe_point": 449843372744245248}],
+			"gc_safe_point": 449843372744245248
+		}`,
+	})
+
+	safePoint, err := client.GCSafePoint()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(449843372744245248), safePoint.GCSafePoint)
+	assert.Equal(t, "gc_worker", safePoint.ServiceGCSafePoints[0].ServiceID)
+}
+
+func TestAPIError(t *testing.T) {
+	client, _ := newTestPD(t, map[string]string{})
+
+	_, err := client.Schedulers()
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "GET /schedulers: 404 Not Found: not found", err.Error())
+}
+
+func TestTLSConfig(t *testing.T) {
+	_, err := TLSConfig([]byte("not a cert"), nil, nil, "merge-pd")
+	assert.EqualError(t, err, "failed to parse CA certificate")
+}
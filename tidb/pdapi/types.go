@@ -0,0 +1,130 @@
+package pdapi
+
+type StoreLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type Store struct {
+	ID            uint64       `json:"id"`
+	Address       string       `json:"address"`
+	StatusAddress string       `json:"status_address"`
+	Labels        []StoreLabel `json:"labels"`
+	Version       string       `json:"version"`
+	StateName     string       `json:"state_name"`
+}
+
+type StoreStatus struct {
+	Capacity        string  `json:"capacity"`
+	Available       string  `json:"available"`
+	UsedSize        string  `json:"used_size"`
+	LeaderCount     int     `json:"leader_count"`
+	LeaderWeight    float64 `json:"leader_weight"`
+	LeaderScore     float64 `json:"leader_score"`
+	RegionCount     int     `json:"region_count"`
+	RegionWeight    float64 `json:"region_weight"`
+	RegionScore     float64 `json:"region_score"`
+	StartTS         string  `json:"start_ts"`
+	LastHeartbeatTS string  `json:"last_heartbeat_ts"`
+	Uptime          string  `json:"uptime"`
+}
+
+type StoreInfo struct {
+	Store  Store       `json:"store"`
+	Status StoreStatus `json:"status"`
+}
+
+// StoresInfo is the response of /pd/api/v1/stores.
+type StoresInfo struct {
+	Count  int         `json:"count"`
+	Stores []StoreInfo `json:"stores"`
+}
+
+type RegionEpoch struct {
+	ConfVer uint64 `json:"conf_ver"`
+	Version uint64 `json:"version"`
+}
+
+type Peer struct {
+	ID       uint64 `json:"id"`
+	StoreID  uint64 `json:"store_id"`
+	RoleName string `json:"role_name"`
+}
+
+// Region is a region as returned by PD. Keys are hex encoded.
+type Region struct {
+	ID              uint64      `json:"id"`
+	StartKey        string      `json:"start_key"`
+	EndKey          string      `json:"end_key"`
+	RegionEpoch     RegionEpoch `json:"epoch"`
+	Peers           []Peer      `json:"peers"`
+	Leader          Peer        `json:"leader"`
+	DownPeers       []Peer      `json:"down_peers"`
+	PendingPeers    []Peer      `json:"pending_peers"`
+	WrittenBytes    uint64      `json:"written_bytes"`
+	ReadBytes       uint64      `json:"read_bytes"`
+	ApproximateSize int64       `json:"approximate_size"`
+	ApproximateKeys int64       `json:"approximate_keys"`
+}
+
+// RegionsInfo is the response of /pd/api/v1/regions.
+type RegionsInfo struct {
+	Count   int      `json:"count"`
+	Regions []Region `json:"regions"`
+}
+
+type HotPeerStat struct {
+	StoreID   uint64  `json:"store_id"`
+	RegionID  uint64  `json:"region_id"`
+	IsLeader  bool    `json:"is_leader"`
+	HotDegree int     `json:"hot_degree"`
+	ByteRate  float64 `json:"flow_bytes"`
+	KeyRate   float64 `json:"flow_keys"`
+	QueryRate float64 `json:"flow_query"`
+}
+
+type HotPeersStat struct {
+	TotalBytesRate float64       `json:"total_flow_bytes"`
+	TotalKeysRate  float64       `json:"total_flow_keys"`
+	TotalQueryRate float64       `json:"total_flow_query"`
+	Count          int           `json:"regions_count"`
+	Stats          []HotPeerStat `json:"statistics"`
+}
+
+// HotRegions is the response of /pd/api/v1/hotspot/regions/{read,write}, with
+// the hot peers by store id.
+type HotRegions struct {
+	AsPeer   map[uint64]HotPeersStat `json:"as_peer"`
+	AsLeader map[uint64]HotPeersStat `json:"as_leader"`
+}
+
+type Member struct {
+	Name          string   `json:"name"`
+	MemberID      uint64   `json:"member_id"`
+	PeerURLs      []string `json:"peer_urls"`
+	ClientURLs    []string `json:"client_urls"`
+	BinaryVersion string   `json:"binary_version"`
+}
+
+// Members is the response of /pd/api/v1/members.
+type Members struct {
+	Members    []Member `json:"members"`
+	Leader     Member   `json:"leader"`
+	EtcdLeader Member   `json:"etcd_leader"`
+}
+
+// Config is the PD config, by section, ie. schedule or replication. It is left
+// untyped as it varies between PD versions.
+type Config map[string]any
+
+type ServiceSafePoint struct {
+	ServiceID string `json:"service_id"`
+	ExpiredAt int64  `json:"expired_at"`
+	SafePoint uint64 `json:"safe_point"`
+}
+
+// GCSafePoint is the response of /pd/api/v1/gc/safepoint.
+type GCSafePoint struct {
+	ServiceGCSafePoints []ServiceSafePoint `json:"service_gc_safe_points"`
+	GCSafePoint         uint64             `json:"gc_safe_point"`
+}
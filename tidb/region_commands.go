@@ -0,0 +1,512 @@
+package tidb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/keycodec"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	defaultTableRegionLimit = 1000
+	defaultHotRegionLimit   = 10
+	// Regions fetched from PD per request when listing a table's regions
+	regionPageSize = 1000
+)
+
+type regionPeer struct {
+	ID      uint64 `json:"id"`
+	StoreID uint64 `json:"storeId"`
+	Pod     string `json:"pod"`
+	Role    string `json:"role"`
+	Leader  bool   `json:"leader"`
+	Down    bool   `json:"down"`
+	Pending bool   `json:"pending"`
+}
+
+// regionRow is a region shown by `mdcli tidb region`, with its keys decoded
+// and its peers mapped to pods.
+type regionRow struct {
+	ID          uint64       `json:"id"`
+	StartKey    string       `json:"startKey"`
+	EndKey      string       `json:"endKey"`
+	Start       string       `json:"start"`
+	End         string       `json:"end"`
+	ConfVer     uint64       `json:"confVer"`
+	Version     uint64       `json:"version"`
+	Peers       []regionPeer `json:"peers"`
+	LeaderPod   string       `json:"leaderPod"`
+	SizeMiB     int64        `json:"approximateSizeMiB"`
+	Keys        int64        `json:"approximateKeys"`
+	ReadBytes   uint64       `json:"readBytes"`
+	WriteBytes  uint64       `json:"writtenBytes"`
+	HotKind     string       `json:"hotKind,omitempty"`
+	HotBytes    float64      `json:"hotBytesRate,omitempty"`
+	HotKeys     float64      `json:"hotKeysRate,omitempty"`
+	HotDegree   int          `json:"hotDegree,omitempty"`
+	HotStoreID  uint64       `json:"hotStoreId,omitempty"`
+	HotStorePod string       `json:"hotStorePod,omitempty"`
+}
+
+// storePods maps the TiKV and TiFlash store ids of the cluster to their pod
+// names. Older operator versions don't set podName, so it falls back to the
+// first label of the store IP, which is the pod's peer DNS name.
+func storePods(tc *model.TidbCluster) map[uint64]string {
+	pods := make(map[uint64]string)
+	for _, stores := range []map[string]model.TiKVStore{tc.Status.TiKV.Stores, tc.Status.TiFlash.Stores} {
+		for _, store := range stores {
+			id, err := store.ID64()
+			if err != nil {
+				continue
+			}
+			pod := store.PodName
+			if pod == "" {
+				pod, _, _ = strings.Cut(store.IP, ".")
+			}
+			pods[id] = pod
+		}
+	}
+	return pods
+}
+
+// formatRegionKey decodes a hex encoded region boundary, ie. to t45_r100. The
+// empty key is the start or end of the keyspace.
+func formatRegionKey(hexKey string, end bool) string {
+	if hexKey == "" {
+		if end {
+			return "+inf"
+		}
+		return "-inf"
+	}
+
+	b, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return hexKey
+	}
+	key, err := keycodec.Decode(b)
+	if err != nil {
+		return hexKey
+	}
+	return key.String()
+}
+
+func newRegionRow(region *pdapi.Region, pods map[uint64]string) regionRow {
+	down := make(map[uint64]bool)
+	for _, peer := range region.DownPeers {
+		down[peer.ID] = true
+	}
+	pending := make(map[uint64]bool)
+	for _, peer := range region.PendingPeers {
+		pending[peer.ID] = true
+	}
+
+	peers := make([]regionPeer, 0, len(region.Peers))
+	for _, peer := range region.Peers {
+		role := peer.RoleName
+		if role == "" {
+			role = "Voter"
+		}
+		peers = append(peers, regionPeer{
+			ID:      peer.ID,
+			StoreID: peer.StoreID,
+			Pod:     pods[peer.StoreID],
+			Role:    role,
+			Leader:  peer.ID == region.Leader.ID,
+			Down:    down[peer.ID],
+			Pending: pending[peer.ID],
+		})
+	}
+
+	return regionRow{
+		ID:         region.ID,
+		StartKey:   region.StartKey,
+		EndKey:     region.EndKey,
+		Start:      formatRegionKey(region.StartKey, false),
+		End:        formatRegionKey(region.EndKey, true),
+		ConfVer:    region.RegionEpoch.ConfVer,
+		Version:    region.RegionEpoch.Version,
+		Peers:      peers,
+		LeaderPod:  pods[region.Leader.StoreID],
+		SizeMiB:    region.ApproximateSize,
+		Keys:       region.ApproximateKeys,
+		ReadBytes:  region.ReadBytes,
+		WriteBytes: region.WrittenBytes,
+	}
+}
+
+// parseRegionArg parses the argument of `mdcli tidb region` as a region id,
+// or otherwise a key as accepted by `mdcli tidb key decode`. Keys are returned
+// memcomparable encoded without the z prefix or timestamp, as PD indexes
+// regions by.
+func parseRegionArg(arg string) (uint64, []byte, error) {
+	// Hex keys are at least a table prefix long, so short numbers are ids
+	if len(arg) < 16 {
+		if id, err := strconv.ParseUint(arg, 10, 64); err == nil {
+			return id, nil, nil
+		}
+	}
+
+	b, err := keycodec.ParseInput(arg)
+	if err != nil {
+		return 0, nil, err
+	}
+	key, err := keycodec.Decode(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if key.DataPrefix {
+		b = b[1:]
+	}
+	if key.Timestamp != 0 {
+		b = b[:len(b)-8]
+	}
+	if !key.Encoded {
+		b = keycodec.EncodeBytes(b)
+	}
+	return 0, b, nil
+}
+
+// tableRegions lists up to limit regions of the table's key ranges, paging
+// through each range and deduplicating as a region may span partitions. It
+// returns whether the limit was hit before every range was listed.
+func tableRegions(client *pdapi.Client, ids []int64, limit int) ([]pdapi.Region, bool, error) {
+	seen := make(map[uint64]bool)
+	regions := make([]pdapi.Region, 0)
+	for _, id := range ids {
+		start := keycodec.EncodeBytes(keycodec.TablePrefix(id))
+		end := keycodec.EncodeBytes(keycodec.TablePrefix(id + 1))
+		for {
+			if len(regions) >= limit {
+				return regions, true, nil
+			}
+
+			info, err := client.RegionsByKey(start, end, min(limit-len(regions), regionPageSize))
+			if err != nil {
+				return nil, false, err
+			}
+			if len(info.Regions) == 0 {
+				break
+			}
+			for _, region := range info.Regions {
+				if !seen[region.ID] {
+					seen[region.ID] = true
+					regions = append(regions, region)
+				}
+			}
+
+			// Continue from the end of the last region until the end of the range
+			last := info.Regions[len(info.Regions)-1].EndKey
+			if last == "" {
+				break
+			}
+			next, err := hex.DecodeString(last)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid region end key '%s': %w", last, err)
+			}
+			if bytes.Compare(next, end) >= 0 || bytes.Compare(next, start) <= 0 {
+				break
+			}
+			start = next
+		}
+	}
+	return regions, false, nil
+}
+
+// hotRegions returns the hottest regions by leader flow for kind read or
+// write, hottest first.
+func hotRegions(client *pdapi.Client, kind string, limit int) ([]pdapi.HotPeerStat, error) {
+	hot, err := client.HotRegions(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]pdapi.HotPeerStat, 0)
+	for _, store := range hot.AsLeader {
+		stats = append(stats, store.Stats...)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ByteRate > stats[j].ByteRate
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// formatBytes formats a byte count or rate in binary units, ie. 1.5 MiB.
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", b, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
+func printRegion(w io.Writer, row regionRow) {
+	fmt.Fprintf(w, "Region:   %d (conf_ver %d, version %d)\n", row.ID, row.ConfVer, row.Version)
+	fmt.Fprintf(w, "Start:    %s\n", row.Start)
+	if row.StartKey != "" {
+		fmt.Fprintf(w, "          %s\n", row.StartKey)
+	}
+	fmt.Fprintf(w, "End:      %s\n", row.End)
+	if row.EndKey != "" {
+		fmt.Fprintf(w, "          %s\n", row.EndKey)
+	}
+	fmt.Fprintf(w, "Size:     %d MiB, %d keys\n", row.SizeMiB, row.Keys)
+	fmt.Fprintf(w, "Leader:   %s\n", orDash(row.LeaderPod))
+	fmt.Fprintln(w)
+
+	rows := make([][]string, 0, len(row.Peers))
+	colors := make([]*color.Color, 0, len(row.Peers))
+	for _, peer := range row.Peers {
+		status := "ok"
+		var c *color.Color
+		switch {
+		case peer.Down:
+			status = "down"
+			c = color.New(color.FgRed)
+		case peer.Pending:
+			status = "pending"
+			c = color.New(color.FgYellow)
+		}
+		role := peer.Role
+		if peer.Leader {
+			role += " (leader)"
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(peer.ID, 10),
+			strconv.FormatUint(peer.StoreID, 10),
+			orDash(peer.Pod),
+			role,
+			status,
+		})
+		colors = append(colors, c)
+	}
+	printTable(w, []string{"PEER", "STORE", "POD", "ROLE", "STATUS"}, rows, colors)
+}
+
+func printRegions(w io.Writer, regions []regionRow) {
+	rows := make([][]string, 0, len(regions))
+	colors := make([]*color.Color, 0, len(regions))
+	for _, region := range regions {
+		rows = append(rows, []string{
+			strconv.FormatUint(region.ID, 10),
+			region.Start,
+			region.End,
+			orDash(region.LeaderPod),
+			fmt.Sprintf("%d MiB", region.SizeMiB),
+			strconv.FormatInt(region.Keys, 10),
+		})
+		colors = append(colors, nil)
+	}
+	printTable(w, []string{"REGION", "START", "END", "LEADER", "SIZE", "KEYS"}, rows, colors)
+}
+
+func printHotRegions(w io.Writer, regions []regionRow) {
+	rows := make([][]string, 0, len(regions))
+	colors := make([]*color.Color, 0, len(regions))
+	for _, region := range regions {
+		rows = append(rows, []string{
+			region.HotKind,
+			strconv.FormatUint(region.ID, 10),
+			orDash(region.HotStorePod),
+			formatBytes(region.HotBytes) + "/s",
+			fmt.Sprintf("%.0f/s", region.HotKeys),
+			strconv.Itoa(region.HotDegree),
+			region.Start,
+			region.End,
+		})
+		colors = append(colors, nil)
+	}
+	printTable(w, []string{"TYPE", "REGION", "LEADER", "BYTES", "KEYS", "DEGREE", "START", "END"}, rows, colors)
+}
+
+func printRegionsJSON(w io.Writer, regions any) error {
+	out, err := json.MarshalIndent(regions, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, string(out))
+	return nil
+}
+
+func tidbRegionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "region",
+		Usage:     "Inspect a region by id or key, the regions of a table, or the hottest regions",
+		ArgsUsage: "[<id|key>]",
+		Flags: append(mdk8s.BaseK8sFlags,
+			&cli.StringFlag{
+				Name:  "table",
+				Usage: "List the regions of `DB.TABLE`",
+			},
+			&cli.BoolFlag{
+				Name:  "hot",
+				Value: false,
+				Usage: "List the hottest read and write regions",
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: fmt.Sprintf("Maximum regions to list, defaults to %d for --table and %d of each type for --hot", defaultTableRegionLimit, defaultHotRegionLimit),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Output `FORMAT`, json for JSON",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			table := cCtx.String("table")
+			hot := cCtx.Bool("hot")
+			output := cCtx.String("output")
+
+			if output != "" && output != "json" {
+				return cli.Exit(fmt.Sprintf("unknown output '%s', must be json", output), 1)
+			}
+			modes := 0
+			for _, set := range []bool{cCtx.NArg() > 0, table != "", hot} {
+				if set {
+					modes++
+				}
+			}
+			if modes != 1 {
+				return cli.Exit("exactly one of a region id or key, --table or --hot must be provided", 1)
+			}
+
+			var db, tableName string
+			if table != "" {
+				var ok bool
+				db, tableName, ok = strings.Cut(table, ".")
+				if !ok || db == "" || tableName == "" {
+					return cli.Exit(fmt.Sprintf("invalid table '%s', must be DB.TABLE", table), 1)
+				}
+			}
+
+			var id uint64
+			var key []byte
+			if cCtx.NArg() > 0 {
+				var err error
+				id, key, err = parseRegionArg(cCtx.Args().First())
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			}
+
+			target, err := resolvePdTarget(cCtx)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			tc, err := model.GetTidbCluster(target.query, model.ClusterName(target.namespace))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			pods := storePods(tc)
+
+			switch {
+			case table != "":
+				info, err := getTableInfo(target.builder, target.context, target.namespace, db, tableName, cCtx.Bool("debug"))
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				limit := cCtx.Int("limit")
+				if limit <= 0 {
+					limit = defaultTableRegionLimit
+				}
+				regions, truncated, err := tableRegions(target.client, info.physicalIDs(), limit)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+
+				rows := make([]regionRow, 0, len(regions))
+				for i := range regions {
+					rows = append(rows, newRegionRow(&regions[i], pods))
+				}
+				if truncated {
+					colorDebugPrintfln(target.context, "Table %s (id %d) has more than %d regions, raise --limit to list them all", table, info.ID, len(rows))
+				}
+				if output == "json" {
+					if err := printRegionsJSON(os.Stdout, rows); err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					return nil
+				}
+				if !truncated {
+					colorDebugPrintfln(target.context, "Table %s (id %d) has %d regions", table, info.ID, len(rows))
+				}
+				printRegions(os.Stdout, rows)
+			case hot:
+				limit := cCtx.Int("limit")
+				if limit <= 0 {
+					limit = defaultHotRegionLimit
+				}
+
+				rows := make([]regionRow, 0)
+				for _, kind := range []string{"read", "write"} {
+					stats, err := hotRegions(target.client, kind, limit)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					for _, stat := range stats {
+						region, err := target.client.Region(stat.RegionID)
+						if err != nil {
+							return cli.Exit(err.Error(), 1)
+						}
+						row := newRegionRow(region, pods)
+						row.HotKind = kind
+						row.HotBytes = stat.ByteRate
+						row.HotKeys = stat.KeyRate
+						row.HotDegree = stat.HotDegree
+						row.HotStoreID = stat.StoreID
+						row.HotStorePod = pods[stat.StoreID]
+						rows = append(rows, row)
+					}
+				}
+				if output == "json" {
+					if err := printRegionsJSON(os.Stdout, rows); err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					return nil
+				}
+				printHotRegions(os.Stdout, rows)
+			default:
+				var region *pdapi.Region
+				if key != nil {
+					region, err = target.client.RegionByKey(key)
+				} else {
+					region, err = target.client.Region(id)
+				}
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+
+				row := newRegionRow(region, pods)
+				if output == "json" {
+					if err := printRegionsJSON(os.Stdout, row); err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					return nil
+				}
+				printRegion(os.Stdout, row)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package tidb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/michaelmdeng/mdcli/tidb/keycodec"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegionArg(t *testing.T) {
+	const encoded = "7480000000000000FF2D5F728000000000FF0000640000000000FA"
+
+	tests := []struct {
+		name  string
+		input string
+		id    uint64
+		key   string
+	}{
+		{name: "id", input: "10", id: 10},
+		{name: "encoded key", input: encoded, key: encoded},
+		{name: "raw key", input: "74800000000000002D5F728000000000000064", key: encoded},
+		{name: "escaped raw key", input: `t\200\000\000\000\000\000\000-_r\200\000\000\000\000\000\000d`, key: encoded},
+		{name: "data key with timestamp", input: "7A" + encoded + "F9C1D5D5FB03FFFF", key: encoded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, key, err := parseRegionArg(test.input)
+			assert.NoError(t, err)
+			assert.Equal(t, test.id, id)
+			assert.Equal(t, test.key, strings.ToUpper(hex.EncodeToString(key)))
+		})
+	}
+
+	_, _, err := parseRegionArg(`t\x8`)
+	assert.Error(t, err)
+}
+
+func TestFormatRegionKey(t *testing.T) {
+	assert.Equal(t, "-inf", formatRegionKey("", false))
+	assert.Equal(t, "+inf", formatRegionKey("", true))
+	assert.Equal(t, "t45_r100", formatRegionKey("7480000000000000FF2D5F728000000000FF0000640000000000FA", false))
+	assert.Equal(t, "t46", formatRegionKey("7480000000000000FF2E00000000000000F8", true))
+	assert.Equal(t, "not hex", formatRegionKey("not hex", false))
+}
+
+func TestNewRegionRow(t *testing.T) {
+	tc := &model.TidbCluster{}
+	tc.Status.TiKV.Stores = map[string]model.TiKVStore{
+		"1":  {ID: "1", PodName: "merge-tikv-0"},
+		"4":  {ID: "4", PodName: "merge-tikv-1"},
+		"10": {ID: "10", IP: "merge-tikv-10.merge-tikv-peer.tidb-merge.svc"},
+	}
+	tc.Status.TiFlash.Stores = map[string]model.TiKVStore{
+		"20": {ID: "20", PodName: "merge-tiflash-0"},
+	}
+	pods := storePods(tc)
+	assert.Equal(t, map[uint64]string{1: "merge-tikv-0", 4: "merge-tikv-1", 10: "merge-tikv-10", 20: "merge-tiflash-0"}, pods)
+
+	row := newRegionRow(&pdapi.Region{
+		ID:          10,
+		StartKey:    "7480000000000000FF2D5F728000000000FF0000640000000000FA",
+		RegionEpoch: pdapi.RegionEpoch{ConfVer: 5, Version: 20},
+		Peers: []pdapi.Peer{
+			{ID: 11, StoreID: 1},
+			{ID: 12, StoreID: 4},
+			{ID: 13, StoreID: 20, RoleName: "Learner"},
+		},
+		Leader:          pdapi.Peer{ID: 12, StoreID: 4},
+		DownPeers:       []pdapi.Peer{{ID: 11, StoreID: 1}},
+		PendingPeers:    []pdapi.Peer{{ID: 13, StoreID: 20}},
+		ApproximateSize: 96,
+		ApproximateKeys: 1000,
+	}, pods)
+
+	assert.Equal(t, "t45_r100", row.Start)
+	assert.Equal(t, "+inf", row.End)
+	assert.Equal(t, "merge-tikv-1", row.LeaderPod)
+	assert.Equal(t, []regionPeer{
+		{ID: 11, StoreID: 1, Pod: "merge-tikv-0", Role: "Voter", Down: true},
+		{ID: 12, StoreID: 4, Pod: "merge-tikv-1", Role: "Voter", Leader: true},
+		{ID: 13, StoreID: 20, Pod: "merge-tiflash-0", Role: "Learner", Pending: true},
+	}, row.Peers)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "3.0 MiB", formatBytes(3*1024*1024))
+}
+
+// newRegionsPD returns a client for a PD stand-in whose regions are split at
+// keys, recording the limit of each regions request.
+func newRegionsPD(t *testing.T, keys [][]byte) (*pdapi.Client, *[]int) {
+	regions := make([]pdapi.Region, 0, len(keys)+1)
+	for i := 0; i <= len(keys); i++ {
+		region := pdapi.Region{ID: uint64(i + 1)}
+		if i > 0 {
+			region.StartKey = strings.ToUpper(hex.EncodeToString(keys[i-1]))
+		}
+		if i < len(keys) {
+			region.EndKey = strings.ToUpper(hex.EncodeToString(keys[i]))
+		}
+		regions = append(regions, region)
+	}
+
+	limits := make([]int, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pd/api/v1/regions/key", r.URL.Path)
+		query := r.URL.Query()
+		start, end := []byte(query.Get("key")), []byte(query.Get("end_key"))
+		limit, err := strconv.Atoi(query.Get("limit"))
+		assert.NoError(t, err)
+		limits = append(limits, limit)
+
+		info := pdapi.RegionsInfo{Regions: make([]pdapi.Region, 0)}
+		for i, region := range regions {
+			if len(info.Regions) == limit {
+				break
+			}
+			// Regions overlapping [start, end)
+			if i < len(keys) && bytes.Compare(keys[i], start) <= 0 {
+				continue
+			}
+			if i > 0 && bytes.Compare(keys[i-1], end) >= 0 {
+				continue
+			}
+			info.Regions = append(info.Regions, region)
+		}
+		info.Count = len(info.Regions)
+		assert.NoError(t, json.NewEncoder(w).Encode(info))
+	}))
+	t.Cleanup(server.Close)
+
+	return pdapi.NewClient(server.URL+"/", nil), &limits
+}
+
+func TestTableRegions(t *testing.T) {
+	keys := [][]byte{
+		keycodec.EncodeBytes(keycodec.TablePrefix(45)),
+		keycodec.EncodeBytes(keycodec.RecordKey(45, 100)),
+		keycodec.EncodeBytes(keycodec.RecordKey(45, 200)),
+		keycodec.EncodeBytes(keycodec.RecordKey(46, 100)),
+		keycodec.EncodeBytes(keycodec.TablePrefix(47)),
+	}
+	ids := func(regions []pdapi.Region) []uint64 {
+		out := make([]uint64, 0, len(regions))
+		for _, region := range regions {
+			out = append(out, region.ID)
+		}
+		return out
+	}
+
+	client, limits := newRegionsPD(t, keys)
+	regions, truncated, err := tableRegions(client, []int64{45, 46}, 10)
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	// Region 4 spans both partitions and is listed once
+	assert.Equal(t, []uint64{2, 3, 4, 5}, ids(regions))
+	assert.Equal(t, []int{10, 7}, *limits)
+
+	// The limit applies across partitions and is reported when hit
+	client, _ = newRegionsPD(t, keys)
+	regions, truncated, err = tableRegions(client, []int64{45, 46}, 3)
+	assert.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Equal(t, []uint64{2, 3, 4}, ids(regions))
+
+	// Tables with more regions than fit in a page are paged through
+	many := make([][]byte, 0, regionPageSize+10)
+	for i := range regionPageSize + 10 {
+		many = append(many, keycodec.EncodeBytes(keycodec.RecordKey(45, int64(i))))
+	}
+	client, limits = newRegionsPD(t, many)
+	regions, truncated, err = tableRegions(client, []int64{45}, 5000)
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	// Including the regions before the first and after the last split
+	assert.Len(t, regions, regionPageSize+11)
+	assert.Equal(t, []int{regionPageSize, regionPageSize}, *limits)
+}
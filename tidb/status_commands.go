@@ -0,0 +1,215 @@
+package tidb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/urfave/cli/v2"
+)
+
+// clusterOverview is the output of `mdcli tidb status`.
+type clusterOverview struct {
+	Name            string                  `json:"name"`
+	Namespace       string                  `json:"namespace"`
+	Paused          bool                    `json:"paused"`
+	Ready           bool                    `json:"ready"`
+	Components      []model.ComponentStatus `json:"components"`
+	UnhealthyStores []model.TiKVStore       `json:"unhealthyStores"`
+	Conditions      []model.Condition       `json:"conditions"`
+}
+
+func newClusterOverview(tc *model.TidbCluster) clusterOverview {
+	return clusterOverview{
+		Name:            tc.Metadata.Name,
+		Namespace:       tc.Metadata.Namespace,
+		Paused:          tc.Spec.Paused,
+		Ready:           tc.Ready(),
+		Components:      tc.Components(),
+		UnhealthyStores: tc.UnhealthyStores(),
+		Conditions:      tc.Status.Conditions,
+	}
+}
+
+// componentColor is green for settled components, yellow for upgrading ones
+// and red for those with missing replicas.
+func componentColor(c model.ComponentStatus) *color.Color {
+	switch {
+	case c.Ready < c.Desired:
+		return color.New(color.FgRed)
+	case c.Upgrading || c.Current != c.Desired:
+		return color.New(color.FgYellow)
+	default:
+		return color.New(color.FgGreen)
+	}
+}
+
+func upgradeStatus(c model.ComponentStatus) string {
+	if !c.Upgrading {
+		return "-"
+	}
+	return fmt.Sprintf("upgrading (%d/%d updated)", c.Updated, c.Current)
+}
+
+// printTable writes the rows aligned under the header, colouring each row
+// after padding so the escape codes don't affect alignment. Rows with a nil
+// colour are left plain.
+func printTable(w io.Writer, header []string, rows [][]string, colors []*color.Color) {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	format := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		return strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+
+	fmt.Fprintln(w, format(header))
+	for i, row := range rows {
+		if colors[i] == nil {
+			fmt.Fprintln(w, format(row))
+		} else {
+			fmt.Fprintln(w, colors[i].Sprint(format(row)))
+		}
+	}
+}
+
+func printClusterOverview(w io.Writer, overview clusterOverview, now time.Time) {
+	fmt.Fprintf(w, "TidbCluster %s/%s", overview.Namespace, overview.Name)
+	if overview.Paused {
+		fmt.Fprint(w, color.RedString(" (reconciliation paused)"))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+
+	rows := make([][]string, 0, len(overview.Components))
+	colors := make([]*color.Color, 0, len(overview.Components))
+	for _, c := range overview.Components {
+		rows = append(rows, []string{
+			c.Name,
+			strconv.Itoa(c.Desired),
+			strconv.Itoa(c.Current),
+			strconv.Itoa(c.Ready),
+			c.Version,
+			c.Phase,
+			upgradeStatus(c),
+		})
+		colors = append(colors, componentColor(c))
+	}
+	printTable(w, []string{"COMPONENT", "DESIRED", "CURRENT", "READY", "VERSION", "PHASE", "UPGRADE"}, rows, colors)
+
+	if len(overview.UnhealthyStores) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.RedString("TiKV stores not Up:"))
+		for _, store := range overview.UnhealthyStores {
+			name := store.PodName
+			if name == "" {
+				name = store.IP
+			}
+			fmt.Fprintf(w, "  %s (store %s): %s, last heartbeat %s ago\n", name, store.ID, store.State, now.Sub(store.LastHeartbeatTime).Round(time.Second))
+		}
+	}
+
+	if len(overview.Conditions) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Conditions:")
+		for _, condition := range overview.Conditions {
+			line := fmt.Sprintf("  %s=%s", condition.Type, condition.Status)
+			if condition.Reason != "" {
+				line += " " + condition.Reason
+			}
+			if condition.Message != "" {
+				line += ": " + condition.Message
+			}
+			if !condition.LastTransitionTime.IsZero() {
+				line += fmt.Sprintf(" (%s ago)", now.Sub(condition.LastTransitionTime).Round(time.Second))
+			}
+			if condition.Status == "True" {
+				fmt.Fprintln(w, line)
+			} else {
+				fmt.Fprintln(w, color.RedString(line))
+			}
+		}
+	}
+}
+
+func tidbStatusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Show the state of the TidbCluster",
+		Flags: append(mdk8s.BaseK8sFlags, &cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   "",
+			Usage:   "Output `FORMAT`, json for JSON instead of a table",
+		}),
+		Action: func(cCtx *cli.Context) error {
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			debug := cCtx.Bool("debug")
+			output := cCtx.String("output")
+
+			if output != "" && output != "json" {
+				return cli.Exit(fmt.Sprintf("unknown output '%s', must be json", output), 1)
+			}
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+
+			builder := NewTidbKubeBuilder()
+			query := kubectlQuery(&builder, context, namespace, false, debug)
+			tc, err := model.GetTidbCluster(query, model.ClusterName(namespace))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			overview := newClusterOverview(tc)
+			if output == "json" {
+				out, err := json.MarshalIndent(overview, "", "  ")
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			printClusterOverview(os.Stdout, overview, time.Now())
+			return nil
+		},
+	}
+}
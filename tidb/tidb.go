@@ -1,18 +1,88 @@
 package tidb
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
-	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
 )
 
 func getTidbSecret(context, namespace string) (string, error) {
-	args := make([]string, 0)
-	args = append(args, "kubectl", "--context", context, "--namespace", namespace, "get", "secret", "tidb-secret", "-o", "json", "|", "jq", "-r", "'.data.root'", "|", "base64", "-d", "|", "tr", "-d", "'\\n'")
-	rootPass, err := mdexec.CaptureCommand("bash", "-c", strings.Join(args, " "))
+	builder := NewTidbKubeBuilder()
+	rootPass, err := builder.SecretKey(context, namespace, "tidb-secret", "root", false)
 	if err != nil {
 		return "", err
 	}
 
-	return rootPass, nil
+	return strings.ReplaceAll(string(rootPass), "\n", ""), nil
+}
+
+type tidbName struct {
+	O string `json:"O"`
+}
+
+// tableInfo is the subset of the table schema returned by the TiDB status API
+// needed to locate its keys.
+type tableInfo struct {
+	ID        int64    `json:"id"`
+	Name      tidbName `json:"name"`
+	Partition *struct {
+		Definitions []struct {
+			ID   int64    `json:"id"`
+			Name tidbName `json:"name"`
+		} `json:"definitions"`
+	} `json:"partition"`
+}
+
+// physicalIDs returns the ids the table's keys are stored under, which are
+// those of its partitions if it is partitioned.
+func (t *tableInfo) physicalIDs() []int64 {
+	if t.Partition == nil || len(t.Partition.Definitions) == 0 {
+		return []int64{t.ID}
+	}
+	ids := make([]int64, 0, len(t.Partition.Definitions))
+	for _, def := range t.Partition.Definitions {
+		ids = append(ids, def.ID)
+	}
+	return ids
+}
+
+// getTableInfo fetches the schema of db.table from the TiDB status API,
+// through a managed port-forward to the TiDB service.
+func getTableInfo(builder *mdk8s.KubeBuilder, context, namespace, db, table string, debug bool) (*tableInfo, error) {
+	baseURL, tlsConfig, err := forwardClusterService(builder, context, namespace, "tidb", tidbStatusPort, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+
+	resp, err := client.Get(fmt.Sprintf("%s/schema/%s/%s", baseURL, url.PathEscape(db), url.PathEscape(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get schema of %s.%s: %s", db, table, strings.TrimSpace(string(data)))
+	}
+
+	var info tableInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode schema of %s.%s: %w", db, table, err)
+	}
+	return &info, nil
 }
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/michaelmdeng/mdcli/internal/config"
 	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,17 +22,100 @@ func BaseTikvCommand() *cli.Command {
 		Subcommands: []*cli.Command{
 			tikvDeleteCommand(),
 			tikvGetCommand(),
+			tikvListCommand(),
+			tikvRollCommand(),
 			tikvStoreCommand(),
 		},
 	}
 }
 
+type k8sList[T any] struct {
+	Items []T `json:"items"`
+}
+
+type pvc struct {
+	Metadata model.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		VolumeName string `json:"volumeName"`
+	} `json:"spec"`
+}
+
+type pv struct {
+	Metadata model.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		CSI struct {
+			VolumeHandle string `json:"volumeHandle"`
+		} `json:"csi"`
+	} `json:"spec"`
+}
+
+type pod struct {
+	Metadata model.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		NodeName string `json:"nodeName"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []model.Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+// ready returns whether the pod's Ready condition is true.
+func (p pod) ready() bool {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == "True"
+		}
+	}
+	return false
+}
+
+type node struct {
+	Metadata model.ObjectMeta `json:"metadata"`
+}
+
+// kubectlQuery returns a function that runs kubectl get with the given args
+// against context and namespace and decodes the JSON output into v.
+func kubectlQuery(builder *mdk8s.KubeBuilder, context, namespace string, allNamespaces bool, debug bool) model.Query {
+	return func(args []string, v any) error {
+		kubectlArgs, _ := builder.BuildKubectlArgs(context, namespace, allNamespaces, false, mdk8s.WithJSONOutput(args))
+		if debug {
+			colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(kubectlArgs, " "))
+		}
+		return mdk8s.DecodeKubectl(kubectlArgs, v)
+	}
+}
+
+// tikvPodName returns the full pod name of a tikv given as its ordinal, ie. 1,
+// tikv-1 or <cluster>-tikv-1.
+func tikvPodName(clusterName, name string) string {
+	name = strings.TrimPrefix(name, clusterName+"-")
+	name = strings.TrimPrefix(name, "tikv-")
+	return fmt.Sprintf("%s-tikv-%s", clusterName, name)
+}
+
+// findTikvStoreId looks up the store id of the tikv pod from the TidbCluster
+// status.
+func findTikvStoreId(query model.Query, clusterName string, tikvName string) (int, error) {
+	tc, err := model.GetTidbCluster(query, clusterName)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := tc.StoreForPod(tikvName)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(store.ID)
+}
+
 func tikvGetCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "get",
 		Usage: "Fetch tikv info",
 		Flags: mdk8s.BaseK8sFlags,
 		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
 			namespace := cCtx.String("namespace")
@@ -40,12 +126,17 @@ func tikvGetCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			context, _, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, allNamespaces, _, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
@@ -55,117 +146,60 @@ func tikvGetCommand() *cli.Command {
 				return cli.Exit("tikv name is required", 1)
 			}
 			clusterName := strings.TrimPrefix(namespace, "tidb-")
-			tikvName = strings.TrimPrefix(tikvName, clusterName+"-")
-			tikvName = strings.TrimPrefix(tikvName, "tikv-")
-			tikvNum := tikvName
-			tikvName = fmt.Sprintf("%s-tikv-%s", clusterName, tikvName)
+			tikvName = tikvPodName(clusterName, tikvName)
 
 			tikvOutput := map[string]any{}
 			tikvOutput["name"] = tikvName
 
 			builder := NewTidbKubeBuilder()
-			args, _ := builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "tc", clusterName, "-o", "jsonpath='{.status.tikv.stores}'"})
+			query := kubectlQuery(&builder, context, namespace, allNamespaces, debug)
 
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
-			}
-
-			output, err := mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
-				return cli.Exit(err.Error(), 1)
-			}
-			output = output[1 : len(output)-1]
-
-			var tikvStores map[string]any
-			err = json.Unmarshal([]byte(output), &tikvStores)
+			storeId, err := findTikvStoreId(query, clusterName, tikvName)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-
-			var storeId int
-			for _, store := range tikvStores {
-				store := store.(map[string]any)
-				if strings.HasPrefix(store["ip"].(string), tikvName) {
-					storeId, err = strconv.Atoi(store["id"].(string))
-					if err != nil {
-						return cli.Exit(err.Error(), 1)
-					}
-				}
-			}
 			tikvOutput["storeId"] = storeId
 
-			dataPvc := fmt.Sprintf("tikv-%s-tikv-%v", clusterName, tikvNum)
-			walPvc := fmt.Sprintf("tikv-wal-%s-tikv-%v", clusterName, tikvNum)
-			raftPvc := fmt.Sprintf("tikv-raft-%s-tikv-%v", clusterName, tikvNum)
-
-			args, _ = builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "pvc", dataPvc, walPvc, raftPvc, "-o", "jsonpath='{.items[*].spec.volumeName}'"})
-
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
-			}
+			dataPvc := fmt.Sprintf("tikv-%s", tikvName)
+			walPvc := fmt.Sprintf("tikv-wal-%s", tikvName)
+			raftPvc := fmt.Sprintf("tikv-raft-%s", tikvName)
 
-			output, err = mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
+			var pvcs k8sList[pvc]
+			if err := query([]string{"get", "pvc", dataPvc, walPvc, raftPvc}, &pvcs); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-			pvs := strings.Split(output[1:len(output)-1], " ")
-			dataPv := pvs[0]
-			walPv := pvs[1]
-			raftPv := pvs[2]
-
-			args, _ = builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "pv", dataPv, walPv, raftPv, "-o", `jsonpath='{range .items[*]}{"{\""}{.metadata.name}{"\":\""}{.spec.csi.volumeHandle}{"\"}\n"}{end}'`})
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
+			pvByPvc := make(map[string]string, len(pvcs.Items))
+			pvNames := make([]string, 0, len(pvcs.Items))
+			for _, item := range pvcs.Items {
+				pvByPvc[item.Metadata.Name] = item.Spec.VolumeName
+				pvNames = append(pvNames, item.Spec.VolumeName)
 			}
 
-			output, err = mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
+			var pvs k8sList[pv]
+			if err := query(append([]string{"get", "pv"}, pvNames...), &pvs); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-			// Trim enclosing quotes and whitespace
-			output = strings.TrimSpace(output[1 : len(output)-1])
-			var pvHandle map[string]string
-			for _, line := range strings.Split(output, "\n") {
-				err = json.Unmarshal([]byte(line), &pvHandle)
-				if err != nil {
-					return cli.Exit(err.Error(), 1)
-				}
-				for pv, handle := range pvHandle {
-					switch pv {
-					case dataPv:
-						tikvOutput["dataVol"] = handle
-					case walPv:
-						tikvOutput["walVol"] = handle
-					case raftPv:
-						tikvOutput["raftVol"] = handle
-					}
+			for _, item := range pvs.Items {
+				switch item.Metadata.Name {
+				case pvByPvc[dataPvc]:
+					tikvOutput["dataVol"] = item.Spec.CSI.VolumeHandle
+				case pvByPvc[walPvc]:
+					tikvOutput["walVol"] = item.Spec.CSI.VolumeHandle
+				case pvByPvc[raftPvc]:
+					tikvOutput["raftVol"] = item.Spec.CSI.VolumeHandle
 				}
 			}
 
-			args, _ = builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "pod", tikvName, "-o", "jsonpath='{.spec.nodeName}'"})
-
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
-			}
-
-			output, err = mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
+			var tikvPod pod
+			if err := query([]string{"get", "pod", tikvName}, &tikvPod); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-			nodeName := output[1 : len(output)-1]
-
-			args, _ = builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "node", nodeName, "-o", "jsonpath='{.metadata.labels.node\\.airbnb\\.com/instance-id}'"})
 
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
-			}
-
-			output, err = mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
+			var tikvNode node
+			if err := query([]string{"get", "node", tikvPod.Spec.NodeName}, &tikvNode); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-			instanceId := output[1 : len(output)-1]
-			tikvOutput["instanceId"] = instanceId
+			tikvOutput["instanceId"] = tikvNode.Metadata.Labels[cfg.K8s.InstanceIdLabel]
 
 			out, err := json.Marshal(tikvOutput)
 			if err != nil {
@@ -194,53 +228,33 @@ func tikvStoreCommand() *cli.Command {
 			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, _, err = ParseContext(context, interactive, "^m-tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			namespace, allNamespaces, _, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
 			tikvName := cCtx.Args().Get(0)
 			clusterName := strings.TrimPrefix(namespace, "tidb-")
-			tikvName = strings.TrimPrefix(tikvName, clusterName+"-")
-			tikvName = strings.TrimPrefix(tikvName, "tikv-")
-			tikvName = fmt.Sprintf("%s-tikv-%s", clusterName, tikvName)
+			tikvName = tikvPodName(clusterName, tikvName)
 
 			builder := NewTidbKubeBuilder()
-			args, _ := builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"get", "tc", clusterName, "-o", "jsonpath='{.status.tikv.stores}'"})
-
-			if debug {
-				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
-			}
-
-			output, err := mdexec.CaptureCommand(mdk8s.Kubectl, args...)
-			if err != nil {
-				return cli.Exit(err.Error(), 1)
-			}
-			output = output[1 : len(output)-1]
+			query := kubectlQuery(&builder, context, namespace, allNamespaces, debug)
 
-			var tikvStores map[string]any
-			err = json.Unmarshal([]byte(output), &tikvStores)
+			storeId, err := findTikvStoreId(query, clusterName, tikvName)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
-
-			var storeId int
-			for _, store := range tikvStores {
-				store := store.(map[string]any)
-				if strings.HasPrefix(store["ip"].(string), tikvName) {
-					storeId, err = strconv.Atoi(store["id"].(string))
-					if err != nil {
-						return cli.Exit(err.Error(), 1)
-					}
-
-					fmt.Println(storeId)
-				}
-			}
+			fmt.Println(storeId)
 
 			return nil
 		},
@@ -249,45 +263,115 @@ func tikvStoreCommand() *cli.Command {
 
 func tikvDeleteCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "delete",
-		Usage: "Delete tikv store pod safely",
-		Flags: mdk8s.BaseK8sFlags,
+		Name:      "delete",
+		Usage:     "Delete tikv store pod safely, evicting its leaders first",
+		ArgsUsage: "<pod>",
+		Flags: append(mdk8s.BaseK8sFlags,
+			&cli.BoolFlag{
+				Name:  "wait",
+				Value: false,
+				Usage: "Wait until the pod has been deleted and recreated Ready",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 30 * time.Minute,
+				Usage: "`TIMEOUT` for each step with --wait, ie. draining leaders",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Value:   false,
+				Usage:   "Automatic yes to confirmation prompts",
+			},
+		),
 		Action: func(cCtx *cli.Context) error {
 			strict := cCtx.Bool("strict")
 			context := cCtx.String("context")
 			namespace := cCtx.String("namespace")
 			interactive := cCtx.Bool("interactive")
 			debug := cCtx.Bool("debug") && !mdexec.IsPipe()
-			allNamespaces := cCtx.Bool("all-namespaces")
+
+			if cCtx.NArg() != 1 {
+				return cli.Exit("tikv name is required", 1)
+			}
+
+			context = inferContextFromNamespace(context, namespace)
 
 			var err error
-			context, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			namespace, allNamespaces, err = ParseNamespace(namespace, allNamespaces, interactive, context, "^tidb-", strict)
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
 			if err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 
-			tikvName := cCtx.Args().Get(0)
-			clusterName := strings.TrimPrefix(namespace, "tidb-")
-			tikvName = strings.TrimPrefix(tikvName, clusterName+"-")
-			tikvName = strings.TrimPrefix(tikvName, "tikv-")
-			tikvName = fmt.Sprintf("%s-tikv-%s", clusterName, tikvName)
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+
+			clusterName := model.ClusterName(namespace)
+			tikvName := tikvPodName(clusterName, cCtx.Args().First())
 
 			builder := NewTidbKubeBuilder()
-			args, _ := builder.BuildKubectlArgs(context, namespace, allNamespaces, false, []string{"annotate", "pod", tikvName, "tidb.pingcap.com/evict-leader=delete-pod"})
+			query := kubectlQuery(&builder, context, namespace, false, debug)
+			roller := &tikvRoller{
+				query:       query,
+				clusterName: clusterName,
+				timeout:     cCtx.Duration("timeout"),
+				interval:    rollPollInterval,
+			}
+
+			tikvPod, ok, err := roller.getPod(tikvName)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if !ok {
+				return cli.Exit(fmt.Sprintf("pod %s not found", tikvName), 1)
+			}
+			tc, err := model.GetTidbCluster(query, clusterName)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			fmt.Printf("Pod: %s (%s)\n", tikvName, tikvPod.Spec.NodeName)
+			if store, err := tc.StoreForPod(tikvName); err == nil {
+				fmt.Printf("Store: %s, %s, %d leaders\n", store.ID, store.State, store.LeaderCount)
+			} else {
+				fmt.Println("Store: none")
+			}
+			if !cCtx.Bool("yes") && !mdexec.GetConfirmation(fmt.Sprintf("Do you want to evict the leaders of %s and delete it in %s?", tikvName, context)) {
+				return cli.Exit("Command canceled by user", 1)
+			}
 
+			args, _ := builder.BuildKubectlArgs(context, namespace, false, false, []string{"annotate", "pod", tikvName, evictLeaderAnnotation, "--overwrite"})
 			if debug {
 				colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
 			}
+			if err := mdexec.RunCommand(mdk8s.Kubectl, args...); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
 
-			err = mdexec.RunCommand(mdk8s.Kubectl, args...)
-			if err != nil {
+			if !cCtx.Bool("wait") {
+				return nil
+			}
+
+			uids := map[string]string{tikvName: tikvPod.Metadata.UID}
+			if err := roller.waitDrained([]string{tikvName}, uids); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if err := roller.waitRecreated([]string{tikvName}, uids); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
+			fmt.Printf("%s recreated and Ready\n", tikvName)
 			return nil
 		},
 	}
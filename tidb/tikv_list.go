@@ -0,0 +1,357 @@
+package tidb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/michaelmdeng/mdcli/internal/config"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
+	"github.com/urfave/cli/v2"
+)
+
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// tikvVolumes are the volumes of a TiKV pod, by the volume claim template
+// prefixing their PVC name.
+var tikvVolumes = []struct {
+	kind     string
+	template string
+}{
+	{"data", "tikv"},
+	{"wal", "tikv-wal"},
+	{"raft", "tikv-raft"},
+}
+
+var tikvSortKeys = []string{"name", "store", "state", "leaders", "regions", "node", "zone"}
+
+type tikvVolume struct {
+	PVC          string `json:"pvc"`
+	VolumeHandle string `json:"volumeHandle"`
+}
+
+// tikvRow is a TiKV pod listed by `mdcli tidb tikv list`.
+type tikvRow struct {
+	Name        string `json:"name"`
+	Ordinal     int    `json:"-"`
+	StoreID     string `json:"storeId"`
+	StoreState  string `json:"storeState"`
+	LeaderCount int    `json:"leaderCount"`
+	// Region count from PD, nil if PD couldn't be reached
+	RegionCount *int   `json:"regionCount,omitempty"`
+	Node        string `json:"node"`
+	InstanceID  string `json:"instanceId"`
+	Zone        string `json:"zone"`
+	// Volumes by kind, ie. wal
+	Volumes map[string]tikvVolume `json:"volumes"`
+}
+
+// listTikvs collects the TiKV pods of the cluster with one query each for the
+// TidbCluster, pods, PVCs, PVs and nodes.
+func listTikvs(query model.Query, clusterName string, instanceIdLabel string) ([]tikvRow, error) {
+	tc, err := model.GetTidbCluster(query, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := fmt.Sprintf("app.kubernetes.io/component=tikv,app.kubernetes.io/instance=%s", clusterName)
+	var pods k8sList[pod]
+	if err := query([]string{"get", "pods", "-l", selector}, &pods); err != nil {
+		return nil, err
+	}
+	var pvcs k8sList[pvc]
+	if err := query([]string{"get", "pvc", "-l", selector}, &pvcs); err != nil {
+		return nil, err
+	}
+
+	pvNames := make([]string, 0, len(pvcs.Items))
+	for _, item := range pvcs.Items {
+		if item.Spec.VolumeName != "" {
+			pvNames = append(pvNames, item.Spec.VolumeName)
+		}
+	}
+	volumeHandles := make(map[string]string, len(pvNames))
+	if len(pvNames) > 0 {
+		var pvs k8sList[pv]
+		if err := query(append([]string{"get", "pv"}, pvNames...), &pvs); err != nil {
+			return nil, err
+		}
+		for _, item := range pvs.Items {
+			volumeHandles[item.Metadata.Name] = item.Spec.CSI.VolumeHandle
+		}
+	}
+
+	nodeNames := make([]string, 0, len(pods.Items))
+	for _, item := range pods.Items {
+		if item.Spec.NodeName != "" && !slices.Contains(nodeNames, item.Spec.NodeName) {
+			nodeNames = append(nodeNames, item.Spec.NodeName)
+		}
+	}
+	nodeLabels := make(map[string]map[string]string, len(nodeNames))
+	if len(nodeNames) > 0 {
+		var nodes k8sList[node]
+		if err := query(append([]string{"get", "nodes"}, nodeNames...), &nodes); err != nil {
+			return nil, err
+		}
+		for _, item := range nodes.Items {
+			nodeLabels[item.Metadata.Name] = item.Metadata.Labels
+		}
+	}
+
+	rows := make([]tikvRow, 0, len(pods.Items))
+	for _, item := range pods.Items {
+		name := item.Metadata.Name
+		row := tikvRow{
+			Name:       name,
+			Ordinal:    podOrdinal(name),
+			Node:       item.Spec.NodeName,
+			InstanceID: nodeLabels[item.Spec.NodeName][instanceIdLabel],
+			Zone:       nodeLabels[item.Spec.NodeName][zoneLabel],
+			Volumes:    make(map[string]tikvVolume),
+		}
+		if store, err := tc.StoreForPod(name); err == nil {
+			row.StoreID = store.ID
+			row.StoreState = store.State
+			row.LeaderCount = store.LeaderCount
+		}
+		for _, claim := range pvcs.Items {
+			for _, volume := range tikvVolumes {
+				if claim.Metadata.Name == volume.template+"-"+name {
+					row.Volumes[volume.kind] = tikvVolume{
+						PVC:          claim.Metadata.Name,
+						VolumeHandle: volumeHandles[claim.Spec.VolumeName],
+					}
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// addRegionCounts sets the region count of each row from the PD stores.
+func addRegionCounts(rows []tikvRow, stores *pdapi.StoresInfo) {
+	counts := make(map[string]int, len(stores.Stores))
+	for _, store := range stores.Stores {
+		counts[strconv.FormatUint(store.Store.ID, 10)] = store.Status.RegionCount
+	}
+	for i := range rows {
+		if count, ok := counts[rows[i].StoreID]; ok && rows[i].StoreID != "" {
+			rows[i].RegionCount = &count
+		}
+	}
+}
+
+func regionCount(row tikvRow) int {
+	if row.RegionCount == nil {
+		return -1
+	}
+	return *row.RegionCount
+}
+
+// podOrdinal returns the StatefulSet ordinal of the pod, ie. 10 for
+// merge-tikv-10, or -1 if it has none.
+func podOrdinal(name string) int {
+	idx := strings.LastIndex(name, "-")
+	ordinal, err := strconv.Atoi(name[idx+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+// sortTikvs orders the rows by key, breaking ties by pod ordinal.
+func sortTikvs(rows []tikvRow, key string) error {
+	var less func(a, b tikvRow) bool
+	switch key {
+	case "name":
+		less = func(a, b tikvRow) bool { return false }
+	case "store":
+		less = func(a, b tikvRow) bool {
+			ai, _ := strconv.Atoi(a.StoreID)
+			bi, _ := strconv.Atoi(b.StoreID)
+			return ai < bi
+		}
+	case "state":
+		less = func(a, b tikvRow) bool { return a.StoreState < b.StoreState }
+	case "leaders":
+		less = func(a, b tikvRow) bool { return a.LeaderCount > b.LeaderCount }
+	case "regions":
+		less = func(a, b tikvRow) bool { return regionCount(a) > regionCount(b) }
+	case "node":
+		less = func(a, b tikvRow) bool { return a.Node < b.Node }
+	case "zone":
+		less = func(a, b tikvRow) bool { return a.Zone < b.Zone }
+	default:
+		return fmt.Errorf("unknown sort key '%s', must be one of %s", key, strings.Join(tikvSortKeys, ", "))
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if less(rows[i], rows[j]) {
+			return true
+		} else if less(rows[j], rows[i]) {
+			return false
+		}
+		return rows[i].Ordinal < rows[j].Ordinal
+	})
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printTikvs writes the rows as a table, adding the PVC names if wide.
+func printTikvs(w io.Writer, rows []tikvRow, wide bool) {
+	header := []string{"NAME", "STORE", "STATE", "LEADERS", "REGIONS", "NODE", "INSTANCE", "ZONE"}
+	for _, volume := range tikvVolumes {
+		if wide {
+			header = append(header, strings.ToUpper(volume.kind)+"-PVC")
+		}
+		header = append(header, strings.ToUpper(volume.kind)+"-VOLUME")
+	}
+
+	table := make([][]string, 0, len(rows))
+	colors := make([]*color.Color, 0, len(rows))
+	for _, row := range rows {
+		regions := "-"
+		if row.RegionCount != nil {
+			regions = strconv.Itoa(*row.RegionCount)
+		}
+		cells := []string{
+			row.Name,
+			orDash(row.StoreID),
+			orDash(row.StoreState),
+			strconv.Itoa(row.LeaderCount),
+			regions,
+			orDash(row.Node),
+			orDash(row.InstanceID),
+			orDash(row.Zone),
+		}
+		for _, volume := range tikvVolumes {
+			if wide {
+				cells = append(cells, orDash(row.Volumes[volume.kind].PVC))
+			}
+			cells = append(cells, orDash(row.Volumes[volume.kind].VolumeHandle))
+		}
+		table = append(table, cells)
+
+		if row.StoreState == model.StoreStateUp {
+			colors = append(colors, nil)
+		} else {
+			colors = append(colors, color.New(color.FgRed))
+		}
+	}
+	printTable(w, header, table, colors)
+}
+
+func printTikvsJSON(w io.Writer, rows []tikvRow) error {
+	out, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func tikvListCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "list",
+		Aliases: []string{"ls"},
+		Usage:   "List the TiKV pods with their store, region count, node and volumes",
+		Flags: append(mdk8s.BaseK8sFlags,
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Output `FORMAT`, json for JSON or wide to include PVC names",
+			},
+			&cli.StringFlag{
+				Name:  "sort",
+				Value: "name",
+				Usage: fmt.Sprintf("`KEY` to sort by, one of %s", strings.Join(tikvSortKeys, ", ")),
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			cfg := config.FromMetadata(cCtx.App.Metadata)
+
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			debug := cCtx.Bool("debug") && !mdexec.IsPipe()
+			output := cCtx.String("output")
+
+			if output != "" && output != "json" && output != "wide" {
+				return cli.Exit(fmt.Sprintf("unknown output '%s', must be json or wide", output), 1)
+			}
+			sortKey := cCtx.String("sort")
+			if !slices.Contains(tikvSortKeys, sortKey) {
+				return cli.Exit(fmt.Sprintf("unknown sort key '%s', must be one of %s", sortKey, strings.Join(tikvSortKeys, ", ")), 1)
+			}
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+
+			builder := NewTidbKubeBuilder()
+			query := kubectlQuery(&builder, context, namespace, false, debug)
+			rows, err := listTikvs(query, model.ClusterName(namespace), cfg.K8s.InstanceIdLabel)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			// Region counts are only known to PD, so are left out if it can't be
+			// reached
+			if client, err := newPdClient(&builder, context, namespace, debug); err != nil {
+				debugPrintfln("Region counts unavailable: %v", err)
+			} else if stores, err := client.Stores(); err != nil {
+				debugPrintfln("Region counts unavailable: %v", err)
+			} else {
+				addRegionCounts(rows, stores)
+			}
+			if err := sortTikvs(rows, sortKey); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if output == "json" {
+				if err := printTikvsJSON(os.Stdout, rows); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				return nil
+			}
+			printTikvs(os.Stdout, rows, output == "wide")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,170 @@
+package tidb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/michaelmdeng/mdcli/tidb/pdapi"
+	"github.com/stretchr/testify/assert"
+)
+
+const tikvListSelector = "app.kubernetes.io/component=tikv,app.kubernetes.io/instance=merge"
+
+// tikvListFixtures are kubectl get responses by args.
+var tikvListFixtures = map[string]string{
+	"get tc merge": `{"metadata": {"name": "merge"}, "status": {"tikv": {"stores": {
+		"1": {"id": "1", "podName": "merge-tikv-0", "leaderCount": 120, "state": "Up"},
+		"4": {"id": "4", "ip": "merge-tikv-1.merge-tikv-peer.tidb-merge.svc", "leaderCount": 0, "state": "Down"},
+		"10": {"id": "10", "podName": "merge-tikv-10", "leaderCount": 95, "state": "Up"}
+	}}}}`,
+	"get pods -l " + tikvListSelector: `{"items": [
+		{"metadata": {"name": "merge-tikv-10"}, "spec": {"nodeName": "ip-10-0-0-2"}},
+		{"metadata": {"name": "merge-tikv-1"}, "spec": {"nodeName": "ip-10-0-0-1"}},
+		{"metadata": {"name": "merge-tikv-0"}, "spec": {"nodeName": "ip-10-0-0-1"}},
+		{"metadata": {"name": "merge-tikv-2"}, "spec": {}}
+	]}`,
+	"get pvc -l " + tikvListSelector: `{"items": [
+		{"metadata": {"name": "tikv-merge-tikv-0"}, "spec": {"volumeName": "pv-1"}},
+		{"metadata": {"name": "tikv-wal-merge-tikv-0"}, "spec": {"volumeName": "pv-2"}},
+		{"metadata": {"name": "tikv-merge-tikv-1"}, "spec": {"volumeName": "pv-3"}},
+		{"metadata": {"name": "tikv-merge-tikv-10"}, "spec": {}}
+	]}`,
+	"get pv pv-1 pv-2 pv-3": `{"items": [
+		{"metadata": {"name": "pv-1"}, "spec": {"csi": {"volumeHandle": "vol-aaa"}}},
+		{"metadata": {"name": "pv-2"}, "spec": {"csi": {"volumeHandle": "vol-bbb"}}},
+		{"metadata": {"name": "pv-3"}, "spec": {"csi": {"volumeHandle": "vol-ccc"}}}
+	]}`,
+	"get nodes ip-10-0-0-2 ip-10-0-0-1": `{"items": [
+		{"metadata": {"name": "ip-10-0-0-1", "labels": {"node.airbnb.com/instance-id": "i-123", "topology.kubernetes.io/zone": "us-east-1a"}}},
+		{"metadata": {"name": "ip-10-0-0-2", "labels": {"node.airbnb.com/instance-id": "i-456", "topology.kubernetes.io/zone": "us-east-1b"}}}
+	]}`,
+}
+
+func fixtureQuery(t *testing.T, fixtures map[string]string, calls *[]string) model.Query {
+	return func(args []string, v any) error {
+		key := strings.Join(args, " ")
+		*calls = append(*calls, key)
+		data, ok := fixtures[key]
+		if !ok {
+			return fmt.Errorf("no fixture for %s", key)
+		}
+		assert.NoError(t, json.Unmarshal([]byte(data), v))
+		return nil
+	}
+}
+
+func TestListTikvs(t *testing.T) {
+	var calls []string
+	rows, err := listTikvs(fixtureQuery(t, tikvListFixtures, &calls), "merge", "node.airbnb.com/instance-id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"get tc merge",
+		"get pods -l " + tikvListSelector,
+		"get pvc -l " + tikvListSelector,
+		"get pv pv-1 pv-2 pv-3",
+		"get nodes ip-10-0-0-2 ip-10-0-0-1",
+	}, calls)
+
+	assert.Len(t, rows, 4)
+	byName := make(map[string]tikvRow)
+	for _, row := range rows {
+		byName[row.Name] = row
+	}
+
+	assert.Equal(t, tikvRow{
+		Name:        "merge-tikv-0",
+		Ordinal:     0,
+		StoreID:     "1",
+		StoreState:  "Up",
+		LeaderCount: 120,
+		Node:        "ip-10-0-0-1",
+		InstanceID:  "i-123",
+		Zone:        "us-east-1a",
+		Volumes: map[string]tikvVolume{
+			"data": {PVC: "tikv-merge-tikv-0", VolumeHandle: "vol-aaa"},
+			"wal":  {PVC: "tikv-wal-merge-tikv-0", VolumeHandle: "vol-bbb"},
+		},
+	}, byName["merge-tikv-0"])
+
+	// The store is matched by IP for operators which don't set podName
+	assert.Equal(t, "4", byName["merge-tikv-1"].StoreID)
+	assert.Equal(t, "Down", byName["merge-tikv-1"].StoreState)
+
+	assert.Equal(t, "us-east-1b", byName["merge-tikv-10"].Zone)
+	assert.Equal(t, tikvVolume{PVC: "tikv-merge-tikv-10"}, byName["merge-tikv-10"].Volumes["data"])
+
+	// A pending pod without a node or store
+	assert.Equal(t, tikvRow{Name: "merge-tikv-2", Ordinal: 2, Volumes: map[string]tikvVolume{}}, byName["merge-tikv-2"])
+}
+
+func TestListTikvsQueryError(t *testing.T) {
+	var calls []string
+	fixtures := map[string]string{"get tc merge": tikvListFixtures["get tc merge"]}
+	_, err := listTikvs(fixtureQuery(t, fixtures, &calls), "merge", "node.airbnb.com/instance-id")
+	assert.EqualError(t, err, "no fixture for get pods -l "+tikvListSelector)
+}
+
+func TestAddRegionCounts(t *testing.T) {
+	rows := []tikvRow{{Name: "merge-tikv-0", StoreID: "1"}, {Name: "merge-tikv-1", StoreID: "4"}, {Name: "merge-tikv-2"}}
+	addRegionCounts(rows, &pdapi.StoresInfo{Stores: []pdapi.StoreInfo{
+		{Store: pdapi.Store{ID: 1}, Status: pdapi.StoreStatus{RegionCount: 300}},
+		{Store: pdapi.Store{ID: 10}, Status: pdapi.StoreStatus{RegionCount: 200}},
+	}})
+
+	assert.Equal(t, 300, *rows[0].RegionCount)
+	assert.Nil(t, rows[1].RegionCount)
+	assert.Nil(t, rows[2].RegionCount)
+
+	var sb strings.Builder
+	printTikvs(&sb, rows, false)
+	lines := strings.Split(sb.String(), "\n")
+	assert.Equal(t, []string{"NAME", "STORE", "STATE", "LEADERS", "REGIONS"}, strings.Fields(lines[0])[:5])
+	assert.Equal(t, []string{"merge-tikv-0", "1", "-", "0", "300"}, strings.Fields(lines[1])[:5])
+	assert.Equal(t, []string{"merge-tikv-1", "4", "-", "0", "-"}, strings.Fields(lines[2])[:5])
+}
+
+func TestSortTikvs(t *testing.T) {
+	count := func(n int) *int { return &n }
+	rows := []tikvRow{
+		{Name: "merge-tikv-10", Ordinal: 10, StoreID: "10", StoreState: "Up", LeaderCount: 95, RegionCount: count(200), Zone: "us-east-1b"},
+		{Name: "merge-tikv-2", Ordinal: 2, StoreID: "4", StoreState: "Down", LeaderCount: 0, Zone: "us-east-1a"},
+		{Name: "merge-tikv-1", Ordinal: 1, StoreID: "1", StoreState: "Up", LeaderCount: 120, RegionCount: count(300), Zone: "us-east-1a"},
+	}
+	names := func() []string {
+		out := make([]string, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, row.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"name", []string{"merge-tikv-1", "merge-tikv-2", "merge-tikv-10"}},
+		{"store", []string{"merge-tikv-1", "merge-tikv-2", "merge-tikv-10"}},
+		{"state", []string{"merge-tikv-2", "merge-tikv-1", "merge-tikv-10"}},
+		{"leaders", []string{"merge-tikv-1", "merge-tikv-10", "merge-tikv-2"}},
+		{"regions", []string{"merge-tikv-1", "merge-tikv-10", "merge-tikv-2"}},
+		{"zone", []string{"merge-tikv-1", "merge-tikv-2", "merge-tikv-10"}},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			assert.NoError(t, sortTikvs(rows, test.key))
+			assert.Equal(t, test.expected, names())
+		})
+	}
+
+	assert.EqualError(t, sortTikvs(rows, "age"), "unknown sort key 'age', must be one of name, store, state, leaders, regions, node, zone")
+}
+
+func TestPodOrdinal(t *testing.T) {
+	assert.Equal(t, 0, podOrdinal("merge-tikv-0"))
+	assert.Equal(t, 10, podOrdinal("merge-tikv-10"))
+	assert.Equal(t, -1, podOrdinal("merge-tikv"))
+	assert.Equal(t, -1, podOrdinal("tikv"))
+}
@@ -0,0 +1,466 @@
+package tidb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	mdexec "github.com/michaelmdeng/mdcli/internal/cmd"
+	"github.com/michaelmdeng/mdcli/internal/state"
+	mdk8s "github.com/michaelmdeng/mdcli/k8s"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/urfave/cli/v2"
+)
+
+// evictLeaderAnnotation makes the operator evict the store's leaders and then
+// delete its pod.
+const evictLeaderAnnotation = "tidb.pingcap.com/evict-leader=delete-pod"
+
+const rollPollInterval = 10 * time.Second
+
+// rollState is the progress of a `mdcli tidb tikv roll`, saved after each step
+// so an aborted roll can be resumed.
+type rollState struct {
+	Pods []string `json:"pods"`
+	Done []string `json:"done"`
+	// UIDs of the pods annotated but not yet confirmed recreated
+	Annotated map[string]string `json:"annotated"`
+}
+
+func (s *rollState) remaining() []string {
+	remaining := make([]string, 0, len(s.Pods))
+	for _, pod := range s.Pods {
+		if !slices.Contains(s.Done, pod) {
+			remaining = append(remaining, pod)
+		}
+	}
+	return remaining
+}
+
+func rollStatePath(context, namespace string) (string, error) {
+	return state.Path("tidb", "roll", context, namespace+".json")
+}
+
+// tikvRoller restarts TiKV pods a batch at a time, waiting for leaders to
+// drain before each restart and for the stores to recover after.
+type tikvRoller struct {
+	query       model.Query
+	annotate    func(pod string) error
+	clusterName string
+	state       *rollState
+	statePath   string
+	timeout     time.Duration
+	interval    time.Duration
+}
+
+func (r *tikvRoller) save() error {
+	return state.WriteJSON(r.statePath, r.state)
+}
+
+// waitFor polls check until it returns true or the timeout expires, printing
+// its progress whenever it changes.
+func (r *tikvRoller) waitFor(description string, check func() (bool, string, error)) error {
+	deadline := time.Now().Add(r.timeout)
+	last := ""
+	for {
+		done, progress, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if progress != last {
+			fmt.Printf("  waiting for %s: %s\n", description, progress)
+			last = progress
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s", r.timeout, description)
+		}
+		time.Sleep(r.interval)
+	}
+}
+
+// getPod returns the pod, or false if it doesn't exist, ie. while it is being
+// recreated.
+func (r *tikvRoller) getPod(name string) (pod, bool, error) {
+	var pods k8sList[pod]
+	if err := r.query([]string{"get", "pods", "--field-selector", "metadata.name=" + name}, &pods); err != nil {
+		return pod{}, false, err
+	}
+	if len(pods.Items) == 0 {
+		return pod{}, false, nil
+	}
+	return pods.Items[0], true, nil
+}
+
+// checkHealthy returns an error if any store other than those of pods is not
+// Up, so a roll never takes down more stores than intended.
+func (r *tikvRoller) checkHealthy(pods []string) error {
+	tc, err := model.GetTidbCluster(r.query, r.clusterName)
+	if err != nil {
+		return err
+	}
+
+	unhealthy := make([]string, 0)
+	for _, store := range tc.UnhealthyStores() {
+		if !slices.ContainsFunc(pods, func(pod string) bool {
+			s, err := tc.StoreForPod(pod)
+			return err == nil && s.ID == store.ID
+		}) {
+			unhealthy = append(unhealthy, fmt.Sprintf("store %s is %s", store.ID, store.State))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("cluster is unhealthy: %s", strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+// recreated returns the pod if its UID has changed from uid.
+func (r *tikvRoller) recreated(name, uid string) (pod, bool, error) {
+	p, ok, err := r.getPod(name)
+	return p, ok && p.Metadata.UID != uid, err
+}
+
+// waitDrained waits until the stores of the annotated pods have no leaders,
+// or the pods were already recreated.
+func (r *tikvRoller) waitDrained(pods []string, uids map[string]string) error {
+	return r.waitFor("leaders to drain", func() (bool, string, error) {
+		tc, err := model.GetTidbCluster(r.query, r.clusterName)
+		if err != nil {
+			return false, "", err
+		}
+
+		progress := make([]string, 0)
+		for _, name := range pods {
+			_, ok, err := r.recreated(name, uids[name])
+			if err != nil {
+				return false, "", err
+			}
+			if ok {
+				continue
+			}
+			store, err := tc.StoreForPod(name)
+			if err == nil && store.LeaderCount > 0 {
+				progress = append(progress, fmt.Sprintf("%s has %d leaders", name, store.LeaderCount))
+			}
+		}
+		return len(progress) == 0, strings.Join(progress, ", "), nil
+	})
+}
+
+// waitRecreated waits until the pods have new UIDs and are Ready.
+func (r *tikvRoller) waitRecreated(pods []string, uids map[string]string) error {
+	return r.waitFor("pods to be recreated and Ready", func() (bool, string, error) {
+		progress := make([]string, 0)
+		for _, name := range pods {
+			p, ok, err := r.recreated(name, uids[name])
+			if err != nil {
+				return false, "", err
+			}
+			if !ok {
+				progress = append(progress, name+" not recreated")
+			} else if !p.ready() {
+				progress = append(progress, name+" not Ready")
+			}
+		}
+		return len(progress) == 0, strings.Join(progress, ", "), nil
+	})
+}
+
+// waitStoresUp waits until the stores of the pods are Up and have leaders
+// rebalanced back onto them.
+func (r *tikvRoller) waitStoresUp(pods []string) error {
+	return r.waitFor("stores to be Up with leaders", func() (bool, string, error) {
+		tc, err := model.GetTidbCluster(r.query, r.clusterName)
+		if err != nil {
+			return false, "", err
+		}
+
+		progress := make([]string, 0)
+		for _, name := range pods {
+			store, err := tc.StoreForPod(name)
+			if err != nil {
+				progress = append(progress, name+" has no store")
+			} else if store.State != model.StoreStateUp {
+				progress = append(progress, fmt.Sprintf("store %s is %s", store.ID, store.State))
+			} else if store.LeaderCount == 0 {
+				progress = append(progress, fmt.Sprintf("store %s has no leaders", store.ID))
+			}
+		}
+		return len(progress) == 0, strings.Join(progress, ", "), nil
+	})
+}
+
+// roll restarts the batch of pods and waits for their stores to recover.
+func (r *tikvRoller) roll(pods []string) error {
+	if err := r.checkHealthy(pods); err != nil {
+		return err
+	}
+
+	for _, name := range pods {
+		p, ok, err := r.getPod(name)
+		if err != nil {
+			return err
+		}
+
+		// The UID is saved before annotating, so a pod annotated by an aborted
+		// roll is never restarted twice. As the annotation may not have been
+		// applied, it is applied again while the pod has the saved UID.
+		if uid, resuming := r.state.Annotated[name]; resuming {
+			if !ok || p.Metadata.UID != uid {
+				fmt.Printf("Resuming %s, already restarted\n", name)
+				continue
+			}
+			fmt.Printf("Resuming %s\n", name)
+		} else {
+			if !ok {
+				return fmt.Errorf("pod %s not found", name)
+			}
+			r.state.Annotated[name] = p.Metadata.UID
+			if err := r.save(); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Evicting leaders from %s\n", name)
+		if err := r.annotate(name); err != nil {
+			return err
+		}
+	}
+
+	if err := r.waitDrained(pods, r.state.Annotated); err != nil {
+		return err
+	}
+	if err := r.waitRecreated(pods, r.state.Annotated); err != nil {
+		return err
+	}
+	if err := r.waitStoresUp(pods); err != nil {
+		return err
+	}
+
+	for _, name := range pods {
+		delete(r.state.Annotated, name)
+		r.state.Done = append(r.state.Done, name)
+		fmt.Printf("Rolled %s\n", name)
+	}
+	return r.save()
+}
+
+// rollAll rolls the remaining pods maxUnavailable at a time, pausing between
+// batches.
+func (r *tikvRoller) rollAll(maxUnavailable int, pauseBetween time.Duration) error {
+	remaining := r.state.remaining()
+	for len(remaining) > 0 {
+		batch := remaining[:min(maxUnavailable, len(remaining))]
+		if err := r.roll(batch); err != nil {
+			return err
+		}
+
+		remaining = r.state.remaining()
+		if len(remaining) > 0 && pauseBetween > 0 {
+			fmt.Printf("Pausing for %s\n", pauseBetween)
+			time.Sleep(pauseBetween)
+		}
+	}
+	return nil
+}
+
+// tikvPods returns the names of the cluster's TiKV pods in ordinal order.
+func tikvPods(query model.Query, clusterName string) ([]string, error) {
+	var pods k8sList[pod]
+	selector := fmt.Sprintf("app.kubernetes.io/component=tikv,app.kubernetes.io/instance=%s", clusterName)
+	if err := query([]string{"get", "pods", "-l", selector}, &pods); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(pods.Items))
+	for _, item := range pods.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return podOrdinal(names[i]) < podOrdinal(names[j])
+	})
+	return names, nil
+}
+
+// printRollPlan prints the pods to roll with their current store.
+func printRollPlan(tc *model.TidbCluster, rollState *rollState, maxUnavailable int) {
+	fmt.Printf("Rolling %d TiKV pods, %d at a time:\n", len(rollState.Pods), maxUnavailable)
+	for _, name := range rollState.Pods {
+		detail := "no store"
+		if store, err := tc.StoreForPod(name); err == nil {
+			detail = fmt.Sprintf("store %s, %s, %d leaders", store.ID, store.State, store.LeaderCount)
+		}
+		if slices.Contains(rollState.Done, name) {
+			detail = "done"
+		} else if _, ok := rollState.Annotated[name]; ok {
+			detail += ", in progress"
+		}
+		fmt.Printf("  %s (%s)\n", name, detail)
+	}
+}
+
+func tikvRollCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "roll",
+		Usage:     "Restart TiKV pods one at a time, evicting leaders first and waiting for each store to recover",
+		ArgsUsage: "[pods...]",
+		Flags: append(mdk8s.BaseK8sFlags,
+			&cli.BoolFlag{
+				Name:  "all",
+				Value: false,
+				Usage: "Roll every TiKV pod",
+			},
+			&cli.IntFlag{
+				Name:  "max-unavailable",
+				Value: 1,
+				Usage: "`NUMBER` of pods to restart at once",
+			},
+			&cli.DurationFlag{
+				Name:  "pause-between",
+				Value: 0,
+				Usage: "`DURATION` to wait between pods",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 30 * time.Minute,
+				Usage: "`TIMEOUT` for each step, ie. draining leaders",
+			},
+			&cli.BoolFlag{
+				Name:  "reset",
+				Value: false,
+				Usage: "Discard the progress of an aborted roll instead of resuming it",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Value:   false,
+				Usage:   "Automatic yes to confirmation prompts",
+			},
+		),
+		Action: func(cCtx *cli.Context) error {
+			strict := cCtx.Bool("strict")
+			context := cCtx.String("context")
+			namespace := cCtx.String("namespace")
+			interactive := cCtx.Bool("interactive")
+			debug := cCtx.Bool("debug") && !mdexec.IsPipe()
+			maxUnavailable := cCtx.Int("max-unavailable")
+
+			if maxUnavailable < 1 {
+				return cli.Exit("--max-unavailable must be at least 1", 1)
+			}
+			if cCtx.Bool("all") && cCtx.NArg() > 0 {
+				return cli.Exit("either pods or --all must be provided, not both", 1)
+			}
+
+			context = inferContextFromNamespace(context, namespace)
+
+			var err error
+			var contextNote, namespaceNote string
+			context, namespace, err = mdk8s.ParseRecentTarget(context, namespace, cCtx.Bool("recent"), "^m-tidb-")
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			context, contextNote, err = ParseContext(context, interactive, "^m-tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			namespace, _, namespaceNote, err = ParseNamespace(namespace, false, interactive, context, "^tidb-", strict)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if debug {
+				colorDebugStickyUsage(context, contextNote, namespaceNote)
+			}
+
+			clusterName := model.ClusterName(namespace)
+			builder := NewTidbKubeBuilder()
+			query := kubectlQuery(&builder, context, namespace, false, debug)
+
+			statePath, err := rollStatePath(context, namespace)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if cCtx.Bool("reset") {
+				if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return cli.Exit(err.Error(), 1)
+				}
+			}
+
+			rollState := &rollState{Done: make([]string, 0), Annotated: make(map[string]string)}
+			if err := state.ReadJSON(statePath, rollState); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			var pods []string
+			if cCtx.Bool("all") {
+				pods, err = tikvPods(query, clusterName)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+			} else {
+				for _, arg := range cCtx.Args().Slice() {
+					pods = append(pods, tikvPodName(clusterName, arg))
+				}
+			}
+
+			if len(rollState.Pods) > 0 {
+				if len(pods) > 0 && !slices.Equal(pods, rollState.Pods) {
+					return cli.Exit(fmt.Sprintf("a roll of %s is in progress, run without pods to resume it or with --reset to discard it", strings.Join(rollState.Pods, ", ")), 1)
+				}
+				fmt.Printf("Resuming roll from %s\n", statePath)
+			} else if len(pods) == 0 {
+				return cli.Exit("pods or --all must be provided", 1)
+			} else {
+				rollState.Pods = pods
+			}
+
+			tc, err := model.GetTidbCluster(query, clusterName)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			printRollPlan(tc, rollState, maxUnavailable)
+			if !cCtx.Bool("yes") && !mdexec.GetConfirmation("Do you want to roll the above pods?") {
+				return cli.Exit("Command canceled by user", 1)
+			}
+
+			roller := &tikvRoller{
+				query: query,
+				annotate: func(pod string) error {
+					args, _ := builder.BuildKubectlArgs(context, namespace, false, false, []string{"annotate", "pod", pod, evictLeaderAnnotation, "--overwrite"})
+					if debug {
+						colorDebugPrintfln(context, "%s %s", mdk8s.Kubectl, strings.Join(args, " "))
+					}
+					return mdexec.RunCommandDiscardOutput(mdk8s.Kubectl, args...)
+				},
+				clusterName: clusterName,
+				state:       rollState,
+				statePath:   statePath,
+				timeout:     cCtx.Duration("timeout"),
+				interval:    rollPollInterval,
+			}
+			if err := roller.save(); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			if err := roller.rollAll(maxUnavailable, cCtx.Duration("pause-between")); err != nil {
+				return cli.Exit(fmt.Sprintf("%s, rerun to resume", err), 1)
+			}
+
+			if err := os.Remove(statePath); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			fmt.Printf("Rolled %d pods\n", len(rollState.Pods))
+			return nil
+		},
+	}
+}
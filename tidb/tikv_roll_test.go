@@ -0,0 +1,245 @@
+package tidb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michaelmdeng/mdcli/internal/state"
+	"github.com/michaelmdeng/mdcli/tidb/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// Phases a fake TiKV pod goes through once annotated, advancing on each query.
+const (
+	phaseRunning = iota
+	phaseAnnotated
+	phaseDrained
+	phaseDeleted
+	phaseRecreated
+	phaseReady
+	phaseRolled
+)
+
+type fakeTikv struct {
+	phase    int
+	restarts int
+	// Whether the pod's leaders never drain
+	stuck bool
+}
+
+// fakeRollCluster simulates the operator restarting annotated TiKV pods.
+type fakeRollCluster struct {
+	tikvs map[string]*fakeTikv
+	// Annotated pods, in order
+	annotated []string
+	// Most pods restarting at once
+	maxInFlight int
+	err         error
+}
+
+func newFakeRollCluster(pods ...string) *fakeRollCluster {
+	c := &fakeRollCluster{tikvs: make(map[string]*fakeTikv)}
+	for _, name := range pods {
+		c.tikvs[name] = &fakeTikv{phase: phaseRunning}
+	}
+	return c
+}
+
+func (c *fakeRollCluster) inFlight() int {
+	n := 0
+	for _, tikv := range c.tikvs {
+		if tikv.phase > phaseRunning && tikv.phase < phaseRolled {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *fakeRollCluster) annotate(name string) error {
+	c.annotated = append(c.annotated, name)
+	if tikv := c.tikvs[name]; tikv.phase == phaseRunning {
+		tikv.phase = phaseAnnotated
+	}
+	c.maxInFlight = max(c.maxInFlight, c.inFlight())
+	return nil
+}
+
+func (c *fakeRollCluster) advance() {
+	for _, tikv := range c.tikvs {
+		if tikv.phase == phaseAnnotated && tikv.stuck {
+			continue
+		}
+		if tikv.phase > phaseRunning && tikv.phase < phaseRolled {
+			tikv.phase++
+			if tikv.phase == phaseRecreated {
+				tikv.restarts++
+			}
+		}
+		if tikv.phase == phaseRolled {
+			tikv.phase = phaseRunning
+		}
+	}
+}
+
+func (c *fakeRollCluster) query(args []string, v any) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.advance()
+
+	var out any
+	switch {
+	case args[0] == "get" && args[1] == "tc":
+		tc := model.TidbCluster{}
+		tc.Status.TiKV.Stores = make(map[string]model.TiKVStore)
+		for i, name := range slices.Sorted(maps.Keys(c.tikvs)) {
+			tikv := c.tikvs[name]
+			store := model.TiKVStore{ID: fmt.Sprint(i + 1), PodName: name, State: model.StoreStateUp, LeaderCount: 10}
+			switch tikv.phase {
+			case phaseDrained, phaseReady:
+				store.LeaderCount = 0
+			case phaseDeleted, phaseRecreated:
+				store.LeaderCount = 0
+				store.State = model.StoreStateDown
+			}
+			tc.Status.TiKV.Stores[store.ID] = store
+		}
+		out = tc
+	case args[0] == "get" && args[1] == "pods" && args[2] == "--field-selector":
+		name := strings.TrimPrefix(args[3], "metadata.name=")
+		pods := k8sList[pod]{Items: make([]pod, 0)}
+		if tikv, ok := c.tikvs[name]; ok && tikv.phase != phaseDeleted {
+			p := pod{}
+			p.Metadata.Name = name
+			p.Metadata.UID = fmt.Sprintf("%s-%d", name, tikv.restarts)
+			ready := "True"
+			if tikv.phase == phaseRecreated {
+				ready = "False"
+			}
+			p.Status.Conditions = []model.Condition{{Type: "Ready", Status: ready}}
+			pods.Items = append(pods.Items, p)
+		}
+		out = pods
+	default:
+		return fmt.Errorf("unexpected query %v", args)
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func newTestRoller(t *testing.T, cluster *fakeRollCluster, pods ...string) *tikvRoller {
+	return &tikvRoller{
+		query:       cluster.query,
+		annotate:    cluster.annotate,
+		clusterName: "merge",
+		state:       &rollState{Pods: pods, Done: make([]string, 0), Annotated: make(map[string]string)},
+		statePath:   filepath.Join(t.TempDir(), "roll.json"),
+		timeout:     time.Second,
+		interval:    0,
+	}
+}
+
+func TestRoll(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0", "merge-tikv-1", "merge-tikv-2")
+	roller := newTestRoller(t, cluster, "merge-tikv-0", "merge-tikv-1", "merge-tikv-2")
+
+	assert.NoError(t, roller.rollAll(1, 0))
+	assert.Equal(t, []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"}, cluster.annotated)
+	assert.Equal(t, 1, cluster.maxInFlight)
+	assert.Equal(t, []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"}, roller.state.Done)
+	assert.Empty(t, roller.state.Annotated)
+	for _, tikv := range cluster.tikvs {
+		assert.Equal(t, 1, tikv.restarts)
+	}
+
+	var saved rollState
+	assert.NoError(t, state.ReadJSON(roller.statePath, &saved))
+	assert.Equal(t, roller.state.Done, saved.Done)
+}
+
+func TestRollMaxUnavailable(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0", "merge-tikv-1", "merge-tikv-2")
+	roller := newTestRoller(t, cluster, "merge-tikv-0", "merge-tikv-1", "merge-tikv-2")
+
+	assert.NoError(t, roller.rollAll(2, 0))
+	assert.Equal(t, []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"}, cluster.annotated)
+	assert.Equal(t, 2, cluster.maxInFlight)
+	assert.Equal(t, []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"}, roller.state.Done)
+}
+
+func TestRollTimeout(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0", "merge-tikv-1")
+	cluster.tikvs["merge-tikv-0"].stuck = true
+	roller := newTestRoller(t, cluster, "merge-tikv-0", "merge-tikv-1")
+	roller.timeout = 10 * time.Millisecond
+	roller.interval = time.Millisecond
+
+	err := roller.rollAll(1, 0)
+	assert.EqualError(t, err, "timed out after 10ms waiting for leaders to drain")
+	assert.Equal(t, []string{"merge-tikv-0"}, cluster.annotated)
+	assert.Empty(t, roller.state.Done)
+
+	// The annotated pod is saved so a rerun resumes it
+	var saved rollState
+	assert.NoError(t, state.ReadJSON(roller.statePath, &saved))
+	assert.Equal(t, map[string]string{"merge-tikv-0": "merge-tikv-0-0"}, saved.Annotated)
+}
+
+func TestRollResume(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0", "merge-tikv-1", "merge-tikv-2")
+	// merge-tikv-2 was restarted by the aborted roll, merge-tikv-1 was saved
+	// but never annotated
+	cluster.tikvs["merge-tikv-2"].restarts = 1
+
+	statePath := filepath.Join(t.TempDir(), "roll.json")
+	assert.NoError(t, state.WriteJSON(statePath, &rollState{
+		Pods:      []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"},
+		Done:      []string{"merge-tikv-0"},
+		Annotated: map[string]string{"merge-tikv-1": "merge-tikv-1-0", "merge-tikv-2": "merge-tikv-2-0"},
+	}))
+
+	roller := newTestRoller(t, cluster)
+	roller.statePath = statePath
+	assert.NoError(t, state.ReadJSON(statePath, roller.state))
+
+	assert.NoError(t, roller.rollAll(2, 0))
+	assert.Equal(t, []string{"merge-tikv-1"}, cluster.annotated)
+	assert.Equal(t, 0, cluster.tikvs["merge-tikv-0"].restarts)
+	assert.Equal(t, 1, cluster.tikvs["merge-tikv-1"].restarts)
+	assert.Equal(t, 1, cluster.tikvs["merge-tikv-2"].restarts)
+	assert.Equal(t, []string{"merge-tikv-0", "merge-tikv-1", "merge-tikv-2"}, roller.state.Done)
+}
+
+func TestRollUnhealthy(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0", "merge-tikv-1")
+	cluster.tikvs["merge-tikv-1"].phase = phaseDeleted
+	roller := newTestRoller(t, cluster, "merge-tikv-0", "merge-tikv-1")
+
+	assert.EqualError(t, roller.roll([]string{"merge-tikv-0"}), "cluster is unhealthy: store 2 is Down")
+	assert.Empty(t, cluster.annotated)
+}
+
+func TestRollQueryError(t *testing.T) {
+	cluster := newFakeRollCluster("merge-tikv-0")
+	roller := newTestRoller(t, cluster, "merge-tikv-0")
+
+	_, ok, err := roller.getPod("merge-tikv-9")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	cluster.err = errors.New("credentials expired")
+	_, _, err = roller.getPod("merge-tikv-0")
+	assert.EqualError(t, err, "credentials expired")
+	assert.EqualError(t, roller.rollAll(1, 0), "credentials expired")
+}
@@ -0,0 +1,119 @@
+package tidb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tsoLogicalBits = 18
+	tsoLogicalMask = 1<<tsoLogicalBits - 1
+
+	// Integers below these are unix seconds and milliseconds respectively,
+	// anything larger is a TSO.
+	maxUnixSeconds = 1e11
+	maxUnixMillis  = 1e14
+
+	tsoTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// localTimeLayouts are the layouts accepted for times without a zone, which
+// are parsed in the local zone.
+var localTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// composeTSO returns the TSO with physical time t and logical counter.
+func composeTSO(t time.Time, logical int64) uint64 {
+	return uint64(t.UnixMilli())<<tsoLogicalBits | uint64(logical&tsoLogicalMask)
+}
+
+// decomposeTSO returns the physical time and logical counter of the TSO.
+func decomposeTSO(tso uint64) (time.Time, int64) {
+	return time.UnixMilli(int64(tso >> tsoLogicalBits)), int64(tso & tsoLogicalMask)
+}
+
+// parseTSOInput parses a TSO, unix seconds or millis, a time, now, or an
+// offset from now such as now-15m, 15m-ago or -15m, returning the TSO. As
+// arguments starting with a dash are parsed as flags, -15m only works on stdin
+// or after --.
+func parseTSOInput(input string, now time.Time) (uint64, error) {
+	input = strings.TrimSpace(input)
+	if input == "now" {
+		return composeTSO(now, 0), nil
+	}
+
+	offset, isOffset := strings.CutPrefix(input, "now")
+	if !isOffset && (strings.HasPrefix(input, "-") || strings.HasPrefix(input, "+")) {
+		offset, isOffset = input, true
+	}
+	if isOffset {
+		if d, err := time.ParseDuration(offset); err == nil {
+			return composeTSO(now.Add(d), 0), nil
+		}
+	}
+	if ago, ok := strings.CutSuffix(input, "-ago"); ok {
+		if d, err := time.ParseDuration(ago); err == nil {
+			return composeTSO(now.Add(-d), 0), nil
+		}
+	}
+
+	if n, err := strconv.ParseUint(input, 10, 64); err == nil {
+		switch {
+		case n < maxUnixSeconds:
+			return composeTSO(time.Unix(int64(n), 0), 0), nil
+		case n < maxUnixMillis:
+			return composeTSO(time.UnixMilli(int64(n)), 0), nil
+		default:
+			return n, nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, input); err == nil {
+		return composeTSO(t, 0), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, input, time.Local); err == nil {
+			return composeTSO(t, 0), nil
+		}
+	}
+
+	return 0, fmt.Errorf("input '%s' is not a TSO, unix timestamp, time, now or offset such as now-15m", input)
+}
+
+// tsoAge formats how long before now t was, or how long after if in the
+// future.
+func tsoAge(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	if d >= time.Minute {
+		d = d.Round(time.Second)
+	} else {
+		d = d.Round(time.Millisecond)
+	}
+	if future {
+		return "in " + d.String()
+	}
+	return d.String() + " ago"
+}
+
+// tsoInfo is a TSO decoded by `mdcli tidb pd tso`.
+type tsoInfo struct {
+	TSO      uint64
+	Physical time.Time
+	Logical  int64
+}
+
+func newTSOInfo(tso uint64, loc *time.Location) tsoInfo {
+	physical, logical := decomposeTSO(tso)
+	return tsoInfo{TSO: tso, Physical: physical.In(loc), Logical: logical}
+}
@@ -0,0 +1,106 @@
+package tidb
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestDecomposeTSO(t *testing.T) {
+	physical, logical := decomposeTSO(449843372744245251)
+	assert.Equal(t, time.Date(2024, 5, 18, 7, 11, 23, 967000000, time.UTC), physical.UTC())
+	assert.Equal(t, int64(3), logical)
+
+	assert.Equal(t, uint64(449843372744245251), composeTSO(physical, logical))
+}
+
+func TestParseTSOInput(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	local := time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		input    string
+		expected uint64
+	}{
+		{"449843372744245251", 449843372744245251},
+		{"1714564800", composeTSO(now, 0)},
+		{"1714564800123", composeTSO(now.Add(123*time.Millisecond), 0)},
+		{"2024-05-01T12:00:00Z", composeTSO(now, 0)},
+		{"2024-05-01T14:00:00.123+02:00", composeTSO(now.Add(123*time.Millisecond), 0)},
+		{"2024-05-01 08:30:00", composeTSO(local, 0)},
+		{"2024-05-01T08:30:00", composeTSO(local, 0)},
+		{"2024-05-01 08:30", composeTSO(local, 0)},
+		{"now", composeTSO(now, 0)},
+		{" -15m ", composeTSO(now.Add(-15*time.Minute), 0)},
+		{"+1h", composeTSO(now.Add(time.Hour), 0)},
+		{"now-15m", composeTSO(now.Add(-15*time.Minute), 0)},
+		{"now+1h30m", composeTSO(now.Add(90*time.Minute), 0)},
+		{"15m-ago", composeTSO(now.Add(-15*time.Minute), 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tso, err := parseTSOInput(test.input, now)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, tso)
+		})
+	}
+
+	for _, input := range []string{"yesterday", "now-", "now-soon", "ages-ago"} {
+		_, err := parseTSOInput(input, now)
+		assert.Error(t, err, input)
+	}
+}
+
+func runTsoCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	app := cli.NewApp()
+	app.ExitErrHandler = func(_ *cli.Context, err error) {}
+	app.Reader = strings.NewReader(stdin)
+	app.Writer = &out
+	app.ErrWriter = io.Discard
+	app.Commands = []*cli.Command{pdTsoCommand()}
+
+	err := app.Run(append([]string{"mdcli", "tso"}, args...))
+	return out.String(), err
+}
+
+func TestPdTsoCommand(t *testing.T) {
+	out, err := runTsoCommand(t, "", "--utc", "449843372744245251")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "TSO:      449843372744245251\n")
+	assert.Contains(t, out, "Physical: 2024-05-18T07:11:23.967Z\n")
+	assert.Contains(t, out, "Logical:  3\n")
+
+	for _, arg := range []string{"now-15m", "15m-ago"} {
+		out, err = runTsoCommand(t, "", arg)
+		assert.NoError(t, err, arg)
+		assert.Contains(t, out, "Age:      15m0s ago\n", arg)
+	}
+
+	// A leading dash is a flag unless after --
+	_, err = runTsoCommand(t, "", "-15m")
+	assert.Error(t, err)
+	out, err = runTsoCommand(t, "", "--", "-15m")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Age:      15m0s ago\n")
+
+	out, err = runTsoCommand(t, "449843372744245251\n\n-15m\nyesterday\n", "--utc")
+	assert.Error(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "449843372744245251\t449843372744245251\t2024-05-18T07:11:23.967Z\t3", lines[0][:strings.LastIndex(lines[0], "\t")])
+	assert.True(t, strings.HasPrefix(lines[1], "-15m\t"))
+}
+
+func TestTSOAge(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "1h30m0s ago", tsoAge(now.Add(-90*time.Minute-300*time.Millisecond), now))
+	assert.Equal(t, "1.5s ago", tsoAge(now.Add(-1500*time.Millisecond), now))
+	assert.Equal(t, "in 10m0s", tsoAge(now.Add(10*time.Minute), now))
+}